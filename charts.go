@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"multicell/internal"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/colornames"
+)
+
+const (
+	chartWidth  = 360
+	chartHeight = 140
+	chartMargin = 20
+)
+
+type chartSeries struct {
+	label string
+	color color.Color
+	value func(m *internal.TurnMetrics) float64
+}
+
+// StatsCharts draws line charts of the recent metrics in screen coordinates
+type StatsCharts struct {
+	panels [][]chartSeries
+	imd    *imdraw.IMDraw
+	atlas  *text.Atlas
+}
+
+func NewStatsCharts(atlas *text.Atlas) *StatsCharts {
+	population := make([]chartSeries, 0, internal.MaxCellType)
+	for ct := internal.CellType(0); ct < internal.MaxCellType; ct++ {
+		ct := ct
+		population = append(population, chartSeries{
+			label: ct.String(), color: cellTypeColor(ct),
+			value: func(m *internal.TurnMetrics) float64 { return float64(m.Cells[ct]) },
+		})
+	}
+	return &StatsCharts{
+		panels: [][]chartSeries{
+			population,
+			{
+				{
+					label: "organisms", color: colornames.Orange,
+					value: func(m *internal.TurnMetrics) float64 { return float64(m.Organisms) },
+				},
+				{
+					label: "genomes", color: colornames.Violet,
+					value: func(m *internal.TurnMetrics) float64 { return float64(m.Genomes) },
+				},
+				{
+					label: "species", color: colornames.Limegreen,
+					value: func(m *internal.TurnMetrics) float64 { return float64(m.Species) },
+				},
+			},
+			{
+				{
+					label: "energy", color: colornames.Red,
+					value: func(m *internal.TurnMetrics) float64 {
+						return float64(m.CellInventory[internal.ItemTypeEnergy])
+					},
+				},
+			},
+		},
+		imd:   imdraw.New(nil),
+		atlas: atlas,
+	}
+}
+
+// Draw expects the target to use the identity matrix, markerTurn highlights the currently shown turn
+func (c *StatsCharts) Draw(target pixel.Target, bounds pixel.Rect, metrics []internal.TurnMetrics, markerTurn int) {
+	c.imd.Clear()
+	var labels []*text.Text
+	for p, panel := range c.panels {
+		area := pixel.R(
+			bounds.Max.X-chartMargin-chartWidth, bounds.Max.Y-float64(p+1)*(chartHeight+chartMargin),
+			bounds.Max.X-chartMargin, bounds.Max.Y-float64(p+1)*(chartHeight+chartMargin)+chartHeight,
+		)
+		c.imd.Color = pixel.RGBA{A: 0.7}
+		c.imd.Push(area.Min, area.Max)
+		c.imd.Rectangle(0)
+		c.imd.Color = colornames.Gray
+		c.imd.Push(area.Min, area.Max)
+		c.imd.Rectangle(1)
+
+		maxValue := 1.0
+		for i := range metrics {
+			for s := range panel {
+				maxValue = max(maxValue, panel[s].value(&metrics[i]))
+			}
+		}
+		if len(metrics) > 1 {
+			step := area.W() / float64(len(metrics)-1)
+			for s := range panel {
+				c.imd.Color = panel[s].color
+				for i := range metrics {
+					c.imd.Push(pixel.V(
+						area.Min.X+step*float64(i), area.Min.Y+area.H()*panel[s].value(&metrics[i])/maxValue,
+					))
+				}
+				c.imd.Line(1.5)
+			}
+			first, last := metrics[0].Turn, metrics[len(metrics)-1].Turn
+			if markerTurn >= first && markerTurn <= last && last > first {
+				x := area.Min.X + area.W()*float64(markerTurn-first)/float64(last-first)
+				c.imd.Color = colornames.White
+				c.imd.Push(pixel.V(x, area.Min.Y), pixel.V(x, area.Max.Y))
+				c.imd.Line(1)
+			}
+		}
+
+		legend := text.New(pixel.V(area.Min.X+4, area.Max.Y-12), c.atlas)
+		for s := range panel {
+			legend.Color = panel[s].color
+			_, _ = fmt.Fprintf(legend, "%s ", panel[s].label)
+		}
+		legend.Color = colornames.White
+		_, _ = fmt.Fprintf(legend, "\nmax %.0f", maxValue)
+		labels = append(labels, legend)
+	}
+	c.imd.Draw(target)
+	for i := range labels {
+		labels[i].Draw(target, pixel.IM)
+	}
+}
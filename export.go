@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"multicell/internal"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type turnRange struct {
+	from, to int
+}
+
+func (r *turnRange) contains(turn, every int) bool {
+	return r != nil && turn >= r.from && turn <= r.to && (turn-r.from)%every == 0
+}
+
+func parseTurnRange(value string) (*turnRange, error) {
+	if value == "" {
+		return nil, nil
+	}
+	from, to, found := strings.Cut(value, ":")
+	if !found {
+		return nil, fmt.Errorf("turn range %q should look like from:to", value)
+	}
+	result := &turnRange{}
+	var err error
+	if result.from, err = strconv.Atoi(from); err != nil {
+		return nil, err
+	}
+	if result.to, err = strconv.Atoi(to); err != nil {
+		return nil, err
+	}
+	if result.from > result.to {
+		return nil, fmt.Errorf("turn range %q is empty", value)
+	}
+	return result, nil
+}
+
+func parseTurns(value string) (map[int]bool, error) {
+	result := make(map[int]bool)
+	if value == "" {
+		return result, nil
+	}
+	for _, part := range strings.Split(value, ",") {
+		turn, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		result[turn] = true
+	}
+	return result, nil
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
+
+func writeGIF(path string, animation *gif.GIF) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gif.EncodeAll(file, animation)
+}
+
+func exportCommand(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	screenshots := flags.String("screenshots", "", "comma separated turns to save PNG screenshots at")
+	gifTurns := flags.String("gif", "", "turn range from:to to save as an animated GIF")
+	framesTurns := flags.String("frames", "", "turn range from:to to save as a numbered PNG frame sequence")
+	every := flags.Int("every", 1, "amount of turns between animation frames")
+	delay := flags.Int("delay", 4, "GIF frame delay in 100ths of a second")
+	visionMode := flags.Int(
+		"vision", VisionModeCellType, "vision mode: 0 cell type, 1 energy, 2 organism, 3 genome, 4 water, 5 species",
+	)
+	tileSize := flags.Int("tile", 8, "size of a single tile in pixels")
+	outDir := flags.String("out", ".", "directory to write the images to")
+	replayPath := flags.String("replay", "", "recorded history file to render instead of simulating")
+	_ = flags.Parse(args)
+
+	screenshotTurns, err := parseTurns(*screenshots)
+	if err != nil {
+		exitWithError(err)
+	}
+	gifRange, err := parseTurnRange(*gifTurns)
+	if err != nil {
+		exitWithError(err)
+	}
+	framesRange, err := parseTurnRange(*framesTurns)
+	if err != nil {
+		exitWithError(err)
+	}
+	if *visionMode < 0 || *visionMode >= MaxVisionMode {
+		exitWithError(fmt.Errorf("unknown vision mode %d", *visionMode))
+	}
+	*every = max(1, *every)
+	lastTurn := 0
+	for turn := range screenshotTurns {
+		lastTurn = max(lastTurn, turn)
+	}
+	if gifRange != nil {
+		lastTurn = max(lastTurn, gifRange.to)
+	}
+	if framesRange != nil {
+		lastTurn = max(lastTurn, framesRange.to)
+	}
+	if lastTurn == 0 {
+		exitWithError(fmt.Errorf("nothing to export, use -screenshots, -gif or -frames"))
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		exitWithError(err)
+	}
+
+	var runner *Runner
+	if *replayPath != "" {
+		runner = NewReplayRunner(loadHistory(*replayPath))
+	} else {
+		world := internal.NewWorld(internal.WorldSize)
+		world.SeedWorld(internal.DefaultSeedChance, internal.RandomGenomes{})
+		runner = NewRunner(world, nil)
+	}
+	renderer := NewFrameRenderer(loadResources(), *tileSize)
+	animation := &gif.GIF{}
+	for {
+		export, ok := runner.Advance()
+		if !ok || export.Turn() > lastTurn {
+			break
+		}
+		turn := export.Turn()
+		if !screenshotTurns[turn] && !gifRange.contains(turn, *every) && !framesRange.contains(turn, *every) {
+			continue
+		}
+		frame := renderer.Render(export, *visionMode)
+		if screenshotTurns[turn] {
+			path := filepath.Join(*outDir, fmt.Sprintf("screenshot_%06d.png", turn))
+			if err := writePNG(path, frame); err != nil {
+				exitWithError(err)
+			}
+			fmt.Println(path)
+		}
+		if framesRange.contains(turn, *every) {
+			path := filepath.Join(*outDir, fmt.Sprintf("frame_%06d.png", turn))
+			if err := writePNG(path, frame); err != nil {
+				exitWithError(err)
+			}
+		}
+		if gifRange.contains(turn, *every) {
+			paletted := image.NewPaletted(frame.Bounds(), palette.Plan9)
+			draw.Draw(paletted, frame.Bounds(), frame, image.Point{}, draw.Src)
+			animation.Image = append(animation.Image, paletted)
+			animation.Delay = append(animation.Delay, *delay)
+		}
+	}
+	if gifRange != nil {
+		path := filepath.Join(*outDir, "animation.gif")
+		if err := writeGIF(path, animation); err != nil {
+			exitWithError(err)
+		}
+		fmt.Println(path)
+	}
+}
+
+func exitWithError(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
@@ -2,14 +2,19 @@ module multicell
 
 go 1.21
 
+require (
+	github.com/faiface/pixel v0.10.0
+	github.com/google/uuid v1.5.0
+	golang.org/x/image v0.14.0
+	golang.org/x/term v0.15.0
+)
+
 require (
 	github.com/faiface/glhf v0.0.0-20231008131257-c8034b63022b // indirect
 	github.com/faiface/mainthread v0.0.0-20171120011319-8b78f0a41ae3 // indirect
-	github.com/faiface/pixel v0.10.0 // indirect
 	github.com/go-gl/gl v0.0.0-20231021071112-07e5d0ea2e71 // indirect
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20231223183121-56fa3ac82ce7 // indirect
 	github.com/go-gl/mathgl v1.1.0 // indirect
-	github.com/google/uuid v1.5.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	golang.org/x/image v0.14.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
 )
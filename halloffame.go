@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"multicell/internal"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// hallOfFameCommand lists the archived genomes and disassembles the chosen one
+func hallOfFameCommand(args []string) {
+	flags := flag.NewFlagSet("halloffame", flag.ExitOnError)
+	fitness := flags.String("fitness", "", "rank by size, seeds or lineage instead of the saved fitness")
+	limit := flags.Int("n", 20, "amount of genomes to list")
+	show := flags.String("show", "", "rank or genome id prefix to disassemble")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: multicell halloffame [flags] hall_of_fame.json")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		exitWithError(err)
+	}
+	defer file.Close()
+	hallOfFame, err := internal.LoadHallOfFame(file, 0)
+	if err != nil {
+		exitWithError(err)
+	}
+	if *fitness != "" {
+		ranking, err := internal.ParseFitness(*fitness)
+		if err != nil {
+			exitWithError(err)
+		}
+		hallOfFame.SetFitness(ranking)
+	}
+	entries := hallOfFame.Entries()
+
+	if *show != "" {
+		for i := range entries {
+			if strconv.Itoa(i+1) != *show && !strings.HasPrefix(entries[i].ID, *show) {
+				continue
+			}
+			genome, err := entries[i].Genome()
+			if err != nil {
+				exitWithError(err)
+			}
+			fmt.Printf("%s, parent %s\n", entries[i].ID, entries[i].ParentID)
+			for _, line := range genome.Disassemble() {
+				fmt.Println(line)
+			}
+			return
+		}
+		exitWithError(fmt.Errorf("genome %q is not in the hall of fame", *show))
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "rank\t%s\tgenome\tparent\tmax size\tseeds\tlineage turns\n", hallOfFame.Fitness())
+	for i := range entries[:min(*limit, len(entries))] {
+		fmt.Fprintf(
+			table, "%d\t%d\t%s\t%s\t%d\t%d\t%d\n", i+1, entries[i].Score(hallOfFame.Fitness()), entries[i].ID,
+			entries[i].ParentID, entries[i].MaxOrganismSize, entries[i].SeedsProduced, entries[i].LineageTurns,
+		)
+	}
+	if err := table.Flush(); err != nil {
+		exitWithError(err)
+	}
+}
@@ -2,6 +2,8 @@ package internal
 
 type Action interface {
 	Apply(cell *Cell, world *World)
+	// EndsTurn tells whether the cell stops executing genes for the turn after the action
+	EndsTurn() bool
 }
 
 type ActionDoNothing struct {
@@ -16,6 +18,10 @@ func (a *ActionDoNothing) Apply(cell *Cell, world *World) {
 	cell.genomePosition = a.nextGenomePosition
 }
 
+func (a *ActionDoNothing) EndsTurn() bool {
+	return false
+}
+
 type ActionCompareForCell struct {
 	comp                            func(c *Cell, w *World) bool
 	positionIfTrue, positionIfFalse uint8
@@ -29,6 +35,10 @@ func (a *ActionCompareForCell) Apply(cell *Cell, world *World) {
 	}
 }
 
+func (a *ActionCompareForCell) EndsTurn() bool {
+	return false
+}
+
 func NewActionCompareForCell(
 	comp func(c *Cell, w *World) bool, positionIfTrue, positionIfFalse uint8,
 ) *ActionCompareForCell {
@@ -60,20 +70,39 @@ func transformationEnergy(ct CellType) int16 {
 type ActionChangeCellType struct {
 	target CellType
 
-	sproutsAmount      uint8
+	// sproutMask has a bit for every direction relative to the facing, starting with the facing itself
+	sproutMask         uint8
+	sproutPositions    [DirectionMax]uint8
 	nextGenomePosition uint8
 	seedFlyingTimer    int
 }
 
 func NewActionChangeCellType(
-	target CellType, nextGenomePosition, sproutsAmount uint8, seedFlyingTimer int,
+	target CellType, nextGenomePosition, sproutMask uint8, sproutPositions [DirectionMax]uint8, seedFlyingTimer int,
 ) *ActionChangeCellType {
 	return &ActionChangeCellType{
-		target: target, nextGenomePosition: nextGenomePosition, sproutsAmount: sproutsAmount,
-		seedFlyingTimer: seedFlyingTimer,
+		target: target, nextGenomePosition: nextGenomePosition, sproutMask: sproutMask,
+		sproutPositions: sproutPositions, seedFlyingTimer: seedFlyingTimer,
 	}
 }
 
+// sproutDirections returns the relative and absolute directions of the masked sprouts with free tiles
+func (a *ActionChangeCellType) sproutDirections(cell *Cell, world *World) (relative, absolute []Direction) {
+	pos := world.GetPosition(cell)
+	for i := Direction(0); i < DirectionMax; i++ {
+		if a.sproutMask&(1<<i) == 0 {
+			continue
+		}
+		direction := (cell.direction + i) % DirectionMax
+		if world.view.occupied(world.MovedByDirection(pos, direction)) {
+			continue
+		}
+		relative = append(relative, i)
+		absolute = append(absolute, direction)
+	}
+	return relative, absolute
+}
+
 func (a *ActionChangeCellType) Apply(cell *Cell, world *World) {
 	cell.genomePosition = a.nextGenomePosition
 	if cell.cellType == a.target || a.target == CellTypeSeed {
@@ -89,12 +118,21 @@ func (a *ActionChangeCellType) Apply(cell *Cell, world *World) {
 	}
 
 	energyRequired := transformationEnergy(a.target)
+	var relative, absolute []Direction
 	if a.target == CellTypeTrunk {
-		energyRequired += int16(a.sproutsAmount) * SproutSpawnEnergy
+		relative, absolute = a.sproutDirections(cell, world)
+		energyRequired += int16(len(absolute)) * SproutSpawnEnergy
 	}
 	if !cell.CheckEnergy(energyRequired) {
 		return
 	}
+	if cell.cellType == CellTypeSeed {
+		world.countGerminatedSeed()
+	}
+	world.emitLater(cell, CellTransformed{
+		EventHeader: world.header(EventCellTransformed), Position: world.GetPosition(cell), From: cell.cellType,
+		To: a.target, Organism: cell.organismID, Genome: cell.genomeID,
+	})
 	cell.cellType = a.target
 	cell.genomePosition = a.nextGenomePosition
 
@@ -103,29 +141,26 @@ func (a *ActionChangeCellType) Apply(cell *Cell, world *World) {
 		cell.seedFlyingTimer = a.seedFlyingTimer
 	}
 
-	if a.target == CellTypeTrunk {
-		spawned := uint8(0)
-
-		for i := 0; i < int(DirectionMax); i++ {
-			if spawned == a.sproutsAmount {
-				break
-			}
-			spawned += 1
-			futureGenome := world.GetGenome(cell.genomeID).Copy(world)
-			// water comes out of nowhere here
-			newSprout := NewCell(
-				futureGenome.id, CellTypeSprout,
-				Inventory{ItemTypeEnergy: TrunkSpawnEnergy, ItemTypeWater: WaterTransferAmount}, cell.organismID,
-			)
-			newSprout.direction = Direction(i)
-			newSprout.genomePosition = cell.genomePosition + uint8(i)
-			world.RegisterNewCell(
-				newSprout, world.GetPosition(cell).MovedByDirection(newSprout.direction), futureGenome,
-			)
-		}
+	for i := range absolute {
+		futureGenome := world.GetGenome(cell.genomeID).Copy(world, cell)
+		// water comes out of nowhere here
+		newSprout := NewCell(
+			futureGenome.id, CellTypeSprout,
+			Inventory{ItemTypeEnergy: TrunkSpawnEnergy, ItemTypeWater: WaterTransferAmount}, cell.organismID,
+		)
+		newSprout.direction = absolute[i]
+		newSprout.registers = cell.registers
+		newSprout.genomePosition = a.sproutPositions[relative[i]]
+		world.RegisterNewCell(
+			cell, newSprout, world.MovedByDirection(world.GetPosition(cell), newSprout.direction), futureGenome,
+		)
 	}
 }
 
+func (a *ActionChangeCellType) EndsTurn() bool {
+	return true
+}
+
 type ActionMove struct {
 	nextGenomePosition uint8
 }
@@ -138,6 +173,10 @@ func (a *ActionMove) Apply(cell *Cell, world *World) {
 	world.RegisterMove(cell)
 }
 
+func (a *ActionMove) EndsTurn() bool {
+	return true
+}
+
 func NewActionMove(nextGenomePosition uint8) *ActionMove {
 	return &ActionMove{nextGenomePosition: nextGenomePosition}
 }
@@ -160,3 +199,87 @@ func (a *ActionRotate) Apply(cell *Cell, world *World) {
 	cell.direction = cell.direction % DirectionMax
 	cell.genomePosition = a.nextGenomePosition
 }
+
+func (a *ActionRotate) EndsTurn() bool {
+	return false
+}
+
+type ActionSetRegister struct {
+	register           uint8
+	value              uint8
+	nextGenomePosition uint8
+}
+
+func NewActionSetRegister(register, value, nextGenomePosition uint8) *ActionSetRegister {
+	return &ActionSetRegister{register: register, value: value, nextGenomePosition: nextGenomePosition}
+}
+
+func (a *ActionSetRegister) Apply(cell *Cell, world *World) {
+	cell.registers[a.register] = a.value
+	cell.genomePosition = a.nextGenomePosition
+}
+
+func (a *ActionSetRegister) EndsTurn() bool {
+	return false
+}
+
+// ActionIncrementRegister counts up, the register wraps around to zero after 255
+type ActionIncrementRegister struct {
+	register           uint8
+	nextGenomePosition uint8
+}
+
+func NewActionIncrementRegister(register, nextGenomePosition uint8) *ActionIncrementRegister {
+	return &ActionIncrementRegister{register: register, nextGenomePosition: nextGenomePosition}
+}
+
+func (a *ActionIncrementRegister) Apply(cell *Cell, world *World) {
+	cell.registers[a.register] += 1
+	cell.genomePosition = a.nextGenomePosition
+}
+
+func (a *ActionIncrementRegister) EndsTurn() bool {
+	return false
+}
+
+// ActionCopyRegister reads a register of the neighbour the cell faces as it was before the phase
+type ActionCopyRegister struct {
+	register, source   uint8
+	nextGenomePosition uint8
+}
+
+func NewActionCopyRegister(register, source, nextGenomePosition uint8) *ActionCopyRegister {
+	return &ActionCopyRegister{register: register, source: source, nextGenomePosition: nextGenomePosition}
+}
+
+func (a *ActionCopyRegister) Apply(cell *Cell, world *World) {
+	cell.genomePosition = a.nextGenomePosition
+	neighbour, found := world.view.at(world.MovedByDirection(world.GetPosition(cell), cell.direction))
+	if found {
+		cell.registers[a.register] = neighbour.registers[a.source]
+	}
+}
+
+func (a *ActionCopyRegister) EndsTurn() bool {
+	return false
+}
+
+// ActionEmitMorphogen sets the amount of the signal the cell emits every turn, also after it turns into
+// another type
+type ActionEmitMorphogen struct {
+	channel, amount    uint8
+	nextGenomePosition uint8
+}
+
+func NewActionEmitMorphogen(channel, amount, nextGenomePosition uint8) *ActionEmitMorphogen {
+	return &ActionEmitMorphogen{channel: channel, amount: amount, nextGenomePosition: nextGenomePosition}
+}
+
+func (a *ActionEmitMorphogen) Apply(cell *Cell, world *World) {
+	cell.emission[a.channel] = a.amount
+	cell.genomePosition = a.nextGenomePosition
+}
+
+func (a *ActionEmitMorphogen) EndsTurn() bool {
+	return false
+}
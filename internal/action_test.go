@@ -0,0 +1,109 @@
+package internal
+
+import "testing"
+
+func TestRegisterGenesSetAndCountWrappingAround(t *testing.T) {
+	w := withBudget(t, InstructionBudget)
+	cell := genomeCell(
+		w, Position{X: 5, Y: 5}, "g",
+		GeneSetRegister, 1, 254, GeneIncrementRegister, 1, GeneIncrementRegister, 1, GeneSetRegister, 2, 9, GeneMove,
+	)
+	w.CleanupTurn()
+	w.ExecuteCellGenomes()
+	if cell.registers != [RegisterCount]uint8{0, 0, 9, 0} || cell.genomePosition != 11 {
+		t.Fatalf("registers %v at gene %d after setting and counting", cell.registers, cell.genomePosition)
+	}
+}
+
+func TestRegisterConditionJumps(t *testing.T) {
+	for _, c := range []struct {
+		register uint8
+		position uint8
+	}{{3, 6}, {10, 8}} {
+		w := withBudget(t, InstructionBudget)
+		cell := genomeCell(
+			w, Position{X: 5, Y: 5}, "g", GeneIf, GeneCommand(CompareRegister), 1, 10, 0, GeneMove, 0, GeneMove,
+		)
+		cell.registers[1] = c.register
+		w.CleanupTurn()
+		w.ExecuteCellGenomes()
+		if cell.genomePosition != c.position {
+			t.Fatalf("register %d < 10 continued at %d instead of %d", c.register, cell.genomePosition, c.position)
+		}
+	}
+}
+
+func TestCopyRegisterReadsTheNeighbourBeforeThePhase(t *testing.T) {
+	w := withBudget(t, InstructionBudget)
+	reader := genomeCell(w, Position{X: 5, Y: 5}, "reader", GeneCopyRegister, 0, 2, GeneMove)
+	reader.direction = DirectionEast
+	writer := genomeCell(w, Position{X: 6, Y: 5}, "writer", GeneSetRegister, 2, 50, GeneMove)
+	writer.registers[2] = 7
+	alone := genomeCell(w, Position{X: 20, Y: 5}, "reader", GeneCopyRegister, 0, 2, GeneMove)
+	alone.registers[0] = 4
+
+	w.CleanupTurn()
+	w.ExecuteCellGenomes()
+	if reader.registers[0] != 7 || writer.registers[2] != 50 {
+		t.Fatalf("copied %d instead of the 7 the neighbour had before it set 50", reader.registers[0])
+	}
+	if alone.registers[0] != 4 {
+		t.Fatalf("a cell without a neighbour changed its register to %d", alone.registers[0])
+	}
+}
+
+// sproutCell runs a gene turning a sprout facing north into a trunk with the given sprout mask, the sprouts
+// start at the genes 40 to 43 by their direction relative to the facing
+func sproutCell(w *World, mask uint8) *Cell {
+	cell := genomeCell(
+		w, Position{X: 5, Y: 5}, "g", GeneTurnTo, GeneCommand(CellTypeTrunk), 0, GeneCommand(mask), 40, 41, 42, 43,
+	)
+	cell.direction = DirectionNorth
+	cell.registers = [RegisterCount]uint8{1, 2, 3, 4}
+	return cell
+}
+
+func TestSproutMaskIsRelativeToTheFacing(t *testing.T) {
+	w := withBudget(t, InstructionBudget)
+	trunk := sproutCell(w, 0b0101)
+	w.CleanupTurn()
+	w.ExecuteCellGenomes()
+	w.CreateNewCells()
+	if trunk.cellType != CellTypeTrunk {
+		t.Fatalf("the sprout turned into %v", trunk.cellType)
+	}
+	for _, c := range []struct {
+		position  Position
+		direction Direction
+		start     uint8
+	}{{Position{X: 5, Y: 4}, DirectionNorth, 40}, {Position{X: 5, Y: 6}, DirectionSouth, 42}} {
+		sprout := w.GetCellByPosition(c.position)
+		if sprout == nil || sprout.cellType != CellTypeSprout {
+			t.Fatalf("no sprout at %v", c.position)
+		}
+		if sprout.direction != c.direction || sprout.genomePosition != c.start || sprout.registers != trunk.registers {
+			t.Fatalf(
+				"the sprout at %v faces %v from gene %d with the registers %v",
+				c.position, sprout.direction, sprout.genomePosition, sprout.registers,
+			)
+		}
+	}
+	if w.CellCount() != 3 {
+		t.Fatalf("%d cells instead of the trunk and its 2 sprouts", w.CellCount())
+	}
+}
+
+func TestSproutMaskSkipsTakenTiles(t *testing.T) {
+	w := withBudget(t, InstructionBudget)
+	sproutCell(w, 0b0011)
+	w.AddCell(Position{X: 6, Y: 5}, NewCell("g", CellTypeLeaf, Inventory{}, "p"))
+	w.CleanupTurn()
+	w.ExecuteCellGenomes()
+	w.CreateNewCells()
+	if sprout := w.GetCellByPosition(Position{X: 5, Y: 4}); sprout == nil || sprout.cellType != CellTypeSprout {
+		t.Fatal("no sprout in the facing direction")
+	}
+	if w.CellCount() != 3 {
+		t.Fatalf("%d cells instead of the trunk, the sprout and the leaf in the way", w.CellCount())
+	}
+}
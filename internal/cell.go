@@ -1,8 +1,6 @@
 package internal
 
 import (
-	"sync"
-
 	"github.com/google/uuid"
 )
 
@@ -15,6 +13,18 @@ const (
 	MaxItemType
 )
 
+func (t ItemType) String() string {
+	switch t {
+	case ItemTypeEnergy:
+		return "energy"
+	case ItemTypeWater:
+		return "water"
+	case ItemTypeOrganic:
+		return "organic"
+	}
+	panic(t)
+}
+
 func itemSpreadStep(t ItemType) int16 {
 	switch t {
 	case ItemTypeEnergy:
@@ -27,29 +37,29 @@ func itemSpreadStep(t ItemType) int16 {
 	panic(t)
 }
 
-type Inventory map[ItemType]int16
+// Inventory holds an amount for every item type
+type Inventory [MaxItemType]int16
 
+// CellInventory is only changed by its own cell during the parallel phases, so it needs no lock
 type CellInventory struct {
 	inventory Inventory
-
-	inventoryMx sync.Mutex
 }
 
 func (c *CellInventory) GetFromInventory(itemType ItemType) int16 {
-	c.inventoryMx.Lock()
-	defer c.inventoryMx.Unlock()
 	return c.inventory[itemType]
 }
 
 func (c *CellInventory) AddToInventory(itemType ItemType, value int16) int16 {
-	c.inventoryMx.Lock()
-	defer c.inventoryMx.Unlock()
 	c.inventory[itemType] += value
 	c.inventory[itemType] = max(c.minForItemType(itemType), c.inventory[itemType])
 	c.inventory[itemType] = min(c.maxForItemType(itemType), c.inventory[itemType])
 	return c.inventory[itemType]
 }
 
+func (c *CellInventory) setInventory(itemType ItemType, value int16) {
+	c.inventory[itemType] = value
+}
+
 func (c *CellInventory) minForItemType(itemType ItemType) int16 {
 	return 0
 }
@@ -68,6 +78,10 @@ func (c *CellInventory) maxForItemType(itemType ItemType) int16 {
 
 type Cell struct {
 	CellInventory
+	// id is given when the cell is added to the world, earlier cells have lower ids
+	id uint64
+	// index is the slot of the cell in the cell store of the world
+	index           int32
 	genomeID        string
 	genomePosition  uint8
 	cellType        CellType
@@ -76,30 +90,43 @@ type Cell struct {
 	age             int16
 	flowerTimer     int
 	seedFlyingTimer int
+	// landing is set on the last flying turn of a seed, until the move is resolved
+	landing bool
+	// random is split for the cell at the start of every parallel phase
+	random *Random
+	// registers are the memory of the genome, sprouts start with the registers of their trunk
+	registers [RegisterCount]uint8
+	// morphogens are the signal levels of the cell, emission is added to them every turn
+	morphogens [MorphogenCount]int16
+	emission   [MorphogenCount]uint8
 }
 
 func (c *Cell) GetType() CellType {
 	return c.cellType
 }
 
-func (c *Cell) ExecuteGenome(world *World, wg *sync.WaitGroup) {
-	defer wg.Done()
+func (c *Cell) ExecuteGenome(world *World) {
 	if c.cellType != CellTypeSeed && c.cellType != CellTypeSprout {
 		return
 	}
-	var action Action
 	if c.seedFlyingTimer > 0 && c.cellType == CellTypeSeed {
-		action = NewActionMove(0)
+		NewActionMove(0).Apply(c, world)
 		c.seedFlyingTimer -= 1
-	} else {
-		genome := world.GetGenome(c.genomeID)
-		action = genome.ExecutePosition(c.genomePosition, world)
+		c.landing = c.seedFlyingTimer == 0
+		return
+	}
+	genome := world.GetGenome(c.genomeID)
+	// jumps, conditions and rotations chain within the turn until an action ends it or the budget runs out
+	for executed := 0; executed < max(1, world.config.InstructionBudget); executed++ {
+		action := genome.ExecutePosition(c.genomePosition, world, c.random)
+		action.Apply(c, world)
+		if action.EndsTurn() {
+			return
+		}
 	}
-	action.Apply(c, world)
 }
 
-func (c *Cell) ExecuteTypeAction(w *World, wg *sync.WaitGroup) {
-	defer wg.Done()
+func (c *Cell) ExecuteTypeAction(w *World) {
 	switch c.cellType {
 	case CellTypeSprout:
 	case CellTypeTrunk:
@@ -115,20 +142,15 @@ func (c *Cell) ExecuteTypeAction(w *World, wg *sync.WaitGroup) {
 }
 
 func (c *Cell) getSunEnergy(w *World) {
-	pos := w.cellPositions[c]
-	ns := pos.Neighbours()
+	pos := w.GetPosition(c)
+	ns := w.Neighbours(pos)
 	for i := range ns {
-		if !w.Occupied(ns[i]) {
-			continue
-		}
-		if w.GetCellByPosition(ns[i]).cellType == CellTypeLeaf {
+		if neighbour, found := w.view.at(ns[i]); found && neighbour.cellType == CellTypeLeaf {
 			// neighbouring leaves eliminate each other
 			return
 		}
 	}
-	w.inventoryMx.Lock()
-	c.AddToInventory(ItemTypeEnergy, w.inventory[pos][ItemTypeEnergy])
-	w.inventoryMx.Unlock()
+	c.AddToInventory(ItemTypeEnergy, w.soil[w.tileIndex(pos)][ItemTypeEnergy])
 }
 
 func (c *Cell) tryToCreateSeed(w *World) {
@@ -136,7 +158,7 @@ func (c *Cell) tryToCreateSeed(w *World) {
 		c.flowerTimer -= 1
 		return
 	}
-	if c.inventory[ItemTypeEnergy] <= SeedSpawnEnergy+FlowerSpawnEnergy {
+	if c.GetFromInventory(ItemTypeEnergy) <= SeedSpawnEnergy+FlowerSpawnEnergy {
 		return
 	}
 	pos := w.GetPosition(c)
@@ -144,7 +166,7 @@ func (c *Cell) tryToCreateSeed(w *World) {
 	possibleDirections := []Direction{DirectionWest, DirectionEast, DirectionNorth, DirectionSouth}
 	flowerDirectionFree := false
 	for i := range possibleDirections {
-		if !w.Occupied(pos.MovedByDirection(possibleDirections[i])) {
+		if !w.view.occupied(w.MovedByDirection(pos, possibleDirections[i])) {
 			direction = possibleDirections[i]
 			if c.direction == direction {
 				flowerDirectionFree = true
@@ -160,50 +182,50 @@ func (c *Cell) tryToCreateSeed(w *World) {
 	}
 
 	c.flowerTimer = int(FlowerSpawnEnergy)
-	futureGenome := w.GetGenome(c.genomeID).Copy(w)
+	futureGenome := w.GetGenome(c.genomeID).Copy(w, c)
 
 	newSeed := NewCell(futureGenome.id, CellTypeSeed, Inventory{
-		ItemTypeEnergy: SeedSpawnEnergy, ItemTypeWater: c.inventory[ItemTypeWater] - WaterTransferAmount,
+		ItemTypeEnergy: SeedSpawnEnergy, ItemTypeWater: c.GetFromInventory(ItemTypeWater) - WaterTransferAmount,
 	}, uuid.NewString())
-	c.inventory[ItemTypeEnergy] -= SeedSpawnEnergy
+	c.AddToInventory(ItemTypeEnergy, -SeedSpawnEnergy)
 	newSeed.direction = direction
-	c.inventory[ItemTypeWater] = WaterTransferAmount
+	c.setInventory(ItemTypeWater, WaterTransferAmount)
 	newSeed.seedFlyingTimer = c.seedFlyingTimer
 
-	newLocation := pos.MovedByDirection(direction)
-	w.RegisterNewCell(newSeed, newLocation, futureGenome)
+	newLocation := w.MovedByDirection(pos, direction)
+	w.RegisterNewCell(c, newSeed, newLocation, futureGenome)
 }
 
 func (c *Cell) SpendEnergy(w *World) {
 	// TODO: tmp solution
-	tax := EnergyTax
+	tax := w.config.EnergyTax
 	if c.cellType == CellTypeSeed || c.cellType == CellTypeSprout || c.cellType == CellTypeTrunk {
 		tax /= 2
 	}
-	c.inventory[ItemTypeEnergy] = min(MaxEnergy, max(0, c.inventory[ItemTypeEnergy]-tax))
+	c.AddToInventory(ItemTypeEnergy, -tax)
 }
 
 func (c *Cell) Die(w *World) {
-	pos := w.cellPositions[c]
-	w.inventory[pos][ItemTypeOrganic] += transformationEnergy(c.cellType) * rotMultiplier
-	w.inventory[pos][ItemTypeWater] += c.GetFromInventory(ItemTypeWater)
-	delete(w.cellPositions, c)
+	i := w.tileIndex(w.GetPosition(c))
+	w.soil[i][ItemTypeOrganic] += transformationEnergy(c.cellType) * rotMultiplier
+	w.soil[i][ItemTypeWater] += c.GetFromInventory(ItemTypeWater)
+	w.tiles[i] = nil
+	w.cells.remove(c)
 }
 
-func (c *Cell) TooOld() bool {
+func (c *Cell) TooOld(w *World) bool {
 	if c.cellType == CellTypeSeed {
-		return c.age > MaxAge*3
+		return int(c.age) > int(w.config.MaxAge)*3
 	}
-	return c.age > MaxAge
+	return c.age > w.config.MaxAge
 }
 
 func (c *Cell) CheckEnergy(e int16) bool {
-	return c.inventory[ItemTypeEnergy] > e
+	return c.GetFromInventory(ItemTypeEnergy) > e
 }
 
 func (c *Cell) getOrganicEnergy(w *World) {
-	got := w.DrainSquare(w.cellPositions[c], ItemTypeOrganic, OrganicDrainByCell)
-	c.inventory[ItemTypeEnergy] += got
+	w.requestDrain(c, ItemTypeOrganic, ItemTypeEnergy, w.config.OrganicDrainByCell)
 }
 
 func (c *Cell) SpendWater(w *World) {
@@ -215,22 +237,18 @@ func (c *Cell) SpendWater(w *World) {
 		waterTax = int16(0)
 	}
 
-	c.inventory[ItemTypeWater] = max(0, c.inventory[ItemTypeWater]-waterTax)
+	c.AddToInventory(ItemTypeWater, -waterTax)
 }
 
 func (c *Cell) getWater(w *World) {
 	if c.GetFromInventory(ItemTypeWater) >= WaterMaxAmount-WaterExtractionValue*4 {
 		return
 	}
-	got := w.DrainSquare(w.cellPositions[c], ItemTypeWater, WaterExtractionValue)
-	c.inventory[ItemTypeWater] += got
+	w.requestDrain(c, ItemTypeWater, ItemTypeWater, WaterExtractionValue)
 }
 
 func NewCell(genomeID string, ct CellType, inventory Inventory, organismID string) *Cell {
 	c := &Cell{cellType: ct, genomeID: genomeID, organismID: organismID}
-	c.inventory = make(Inventory)
-	for it := range inventory {
-		c.inventory[it] = inventory[it]
-	}
+	c.inventory = inventory
 	return c
 }
@@ -0,0 +1,60 @@
+package internal
+
+import "testing"
+
+// genomeCell places a sprout running the given genes, the rest of its genome passes
+func genomeCell(w *World, pos Position, genomeID string, genes ...GeneCommand) *Cell {
+	genome := &Genome{id: genomeID, genome: make([]uint8, GenomeSize)}
+	for i := range genes {
+		genome.genome[i] = uint8(genes[i])
+	}
+	w.AddGenome(genome)
+	inventory := Inventory{ItemTypeEnergy: 100, ItemTypeWater: 100}
+	return w.AddCell(pos, NewCell(genomeID, CellTypeSprout, inventory, "o"))
+}
+
+func withBudget(t *testing.T, budget int) *World {
+	w := NewSeededWorld(WorldSize, 1)
+	config := w.Config()
+	config.InstructionBudget = budget
+	if err := w.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestGenesChainUpToTheInstructionBudget(t *testing.T) {
+	for _, c := range []struct {
+		budget   int
+		genes    []GeneCommand
+		position uint8
+	}{
+		{3, nil, 3},
+		{0, nil, 1},
+		{8, []GeneCommand{GenePass, GeneMove, GenePass}, 2},
+		{8, []GeneCommand{GeneRotate, GenePass, GeneMove}, 3},
+		{2, []GeneCommand{GeneRotate, GenePass, GeneMove}, 2},
+	} {
+		w := withBudget(t, c.budget)
+		cell := genomeCell(w, Position{X: 5, Y: 5}, "g", c.genes...)
+		w.CleanupTurn()
+		w.ExecuteCellGenomes()
+		if cell.genomePosition != c.position {
+			t.Fatalf(
+				"genes %v with a budget of %d stopped at %d instead of %d",
+				c.genes, c.budget, cell.genomePosition, c.position,
+			)
+		}
+	}
+}
+
+func TestRotationsDoNotEndTheTurn(t *testing.T) {
+	w := withBudget(t, InstructionBudget)
+	cell := genomeCell(w, Position{X: 5, Y: 5}, "g", GeneRotate, GenePass, GeneRotate, GenePass, GeneMove)
+	cell.direction = DirectionWest
+	w.CleanupTurn()
+	w.ExecuteCellGenomes()
+	if cell.direction != DirectionWest+2 || cell.genomePosition != 5 {
+		t.Fatalf("the cell faces %v at gene %d after two rotations and a move", cell.direction, cell.genomePosition)
+	}
+}
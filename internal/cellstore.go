@@ -0,0 +1,55 @@
+package internal
+
+// cellBlockSize is the amount of cells allocated at once, the blocks never move so cell pointers stay valid
+const cellBlockSize = 4096
+
+// cellStore keeps the cells in dense blocks indexed by the cell index, the indexes of removed cells are reused.
+// The positions are a parallel slice, free slots have a zero cell id
+type cellStore struct {
+	blocks    [][]Cell
+	positions []Position
+	free      []int32
+	count     int
+}
+
+func (s *cellStore) at(index int32) *Cell {
+	return &s.blocks[index/cellBlockSize][index%cellBlockSize]
+}
+
+// add copies the cell into a free slot and returns the stored cell
+func (s *cellStore) add(cell *Cell, pos Position) *Cell {
+	var index int32
+	if len(s.free) > 0 {
+		index = s.free[len(s.free)-1]
+		s.free = s.free[:len(s.free)-1]
+	} else {
+		index = int32(len(s.positions))
+		if int(index)%cellBlockSize == 0 {
+			s.blocks = append(s.blocks, make([]Cell, cellBlockSize))
+		}
+		s.positions = append(s.positions, Position{})
+	}
+	stored := s.at(index)
+	*stored = *cell
+	stored.index = index
+	s.positions[index] = pos
+	s.count += 1
+	return stored
+}
+
+func (s *cellStore) remove(cell *Cell) {
+	index := cell.index
+	*cell = Cell{index: index}
+	s.free = append(s.free, index)
+	s.count -= 1
+}
+
+// forEach calls the function for the living cells in index order, the function may remove the cell
+func (s *cellStore) forEach(f func(c *Cell, pos Position)) {
+	for i := range s.positions {
+		cell := s.at(int32(i))
+		if cell.id != 0 {
+			f(cell, s.positions[i])
+		}
+	}
+}
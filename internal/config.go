@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Config holds the simulation parameters which may change between runs or even between turns
+type Config struct {
+	MutationChance         float32
+	EnergyTax              int16
+	OrganicDrainByCell     int16
+	WaterRegenerationValue int16
+	MaxAge                 int16
+	// SpeciesThreshold is the amount of differing genes allowed within a species
+	SpeciesThreshold int
+	// ActivityThreshold is the cumulative amount of cells after which a genome counts as new activity
+	ActivityThreshold int
+	// TransportPasses is the amount of cells resources may travel through within a turn
+	TransportPasses int
+	// FragmentRequiredCellType kills the fragments of split organisms without a cell of the type, -1 keeps them
+	FragmentRequiredCellType int
+	ConflictResolution       ConflictPolicy
+	Scheduler                SchedulerType
+	// Workers is the size of the pool of the chunk scheduler, 0 uses GOMAXPROCS
+	Workers int
+	// InstructionBudget is the amount of genes a cell may execute in a turn, turning and moving end the turn earlier
+	InstructionBudget int
+	// MorphogenDecay is the percentage of every signal lost per turn
+	MorphogenDecay int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MutationChance:           MutationChance,
+		EnergyTax:                EnergyTax,
+		OrganicDrainByCell:       OrganicDrainByCell,
+		WaterRegenerationValue:   WaterRegenerationValue,
+		MaxAge:                   MaxAge,
+		SpeciesThreshold:         SpeciesThreshold,
+		ActivityThreshold:        ActivityThreshold,
+		TransportPasses:          TransportPasses,
+		FragmentRequiredCellType: -1,
+		ConflictResolution:       ConflictFirstByCellID,
+		Scheduler:                SchedulerChunks,
+		InstructionBudget:        InstructionBudget,
+		MorphogenDecay:           MorphogenDecay,
+	}
+}
+
+func (w *World) Config() Config {
+	return w.config
+}
+
+// SetConfig must not be called while a turn is in progress, an invalid config leaves the current one
+func (w *World) SetConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	w.config = config
+	return nil
+}
+
+// Validate rejects the values which the enum parameters do not define
+func (c Config) Validate() error {
+	if c.ConflictResolution >= MaxConflictPolicy {
+		return fmt.Errorf("unknown conflict policy %d", c.ConflictResolution)
+	}
+	if c.Scheduler >= MaxSchedulerType {
+		return fmt.Errorf("unknown scheduler %d", c.Scheduler)
+	}
+	if c.FragmentRequiredCellType < -1 || c.FragmentRequiredCellType >= int(MaxCellType) {
+		return fmt.Errorf("unknown fragment required cell type %d", c.FragmentRequiredCellType)
+	}
+	return nil
+}
+
+// ConfigFieldNames lists the parameters which can be changed with SetField
+func ConfigFieldNames() []string {
+	configType := reflect.TypeOf(Config{})
+	result := make([]string, configType.NumField())
+	for i := range result {
+		result[i] = configType.Field(i).Name
+	}
+	return result
+}
+
+// Field returns a parameter by its name as it is applied, after the rounding of SetField
+func (c *Config) Field(name string) (float64, error) {
+	field := reflect.ValueOf(c).Elem().FieldByName(name)
+	if !field.IsValid() {
+		return 0, fmt.Errorf("unknown config parameter %q", name)
+	}
+	switch field.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), nil
+	}
+	panic(field.Kind())
+}
+
+// SetField changes a parameter by its name, integer parameters are rounded down. The config is left
+// unchanged when the value is not valid
+func (c *Config) SetField(name string, value float64) error {
+	updated := *c
+	field := reflect.ValueOf(&updated).Elem().FieldByName(name)
+	if !field.IsValid() {
+		return fmt.Errorf("unknown config parameter %q", name)
+	}
+	switch field.Kind() {
+	case reflect.Float32, reflect.Float64:
+		field.SetFloat(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.OverflowInt(int64(value)) {
+			return fmt.Errorf("config parameter %s value %v is out of range", name, value)
+		}
+		field.SetInt(int64(value))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value < 0 || field.OverflowUint(uint64(value)) {
+			return fmt.Errorf("config parameter %s value %v is out of range", name, value)
+		}
+		field.SetUint(uint64(value))
+	default:
+		panic(field.Kind())
+	}
+	if err := updated.Validate(); err != nil {
+		return fmt.Errorf("config parameter %s value %v: %w", name, value, err)
+	}
+	*c = updated
+	return nil
+}
@@ -0,0 +1,63 @@
+package internal
+
+import "testing"
+
+func TestSetFieldRejectsUnknownEnumValues(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		value float64
+		valid bool
+	}{
+		{"ConflictResolution", float64(ConflictAllFail), true},
+		{"ConflictResolution", float64(MaxConflictPolicy), false},
+		{"ConflictResolution", 200, false},
+		{"Scheduler", float64(SchedulerPerCell), true},
+		{"Scheduler", float64(MaxSchedulerType), false},
+		{"FragmentRequiredCellType", -1, true},
+		{"FragmentRequiredCellType", float64(CellTypeLeaf), true},
+		{"FragmentRequiredCellType", -2, false},
+		{"FragmentRequiredCellType", float64(MaxCellType), false},
+	} {
+		config := DefaultConfig()
+		err := config.SetField(c.name, c.value)
+		if valid := err == nil; valid != c.valid {
+			t.Fatalf("%s=%v valid: %v, expected %v", c.name, c.value, valid, c.valid)
+		}
+		if !c.valid && config != DefaultConfig() {
+			t.Fatalf("%s=%v changed the config", c.name, c.value)
+		}
+	}
+}
+
+func TestSetConfigKeepsTheValidConfig(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	config := DefaultConfig()
+	config.ConflictResolution = MaxConflictPolicy
+	if err := w.SetConfig(config); err == nil {
+		t.Fatal("an unknown conflict policy was accepted")
+	}
+	if w.Config() != DefaultConfig() {
+		t.Fatal("the invalid config was applied")
+	}
+}
+
+func TestFieldReturnsTheAppliedValue(t *testing.T) {
+	config := DefaultConfig()
+	for _, c := range []struct {
+		name            string
+		value, expected float64
+	}{
+		{"TransportPasses", 2.8139, 2},
+		{"MutationChance", 0.5, 0.5},
+	} {
+		if err := config.SetField(c.name, c.value); err != nil {
+			t.Fatal(err)
+		}
+		if value, err := config.Field(c.name); err != nil || value != c.expected {
+			t.Fatalf("%s=%v is applied as %v (%v), expected %v", c.name, c.value, value, err, c.expected)
+		}
+	}
+	if _, err := config.Field("Unknown"); err == nil {
+		t.Fatal("an unknown parameter was returned")
+	}
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ConflictPolicy decides which of the cells spawning or moving into the same tile succeeds
+type ConflictPolicy uint8
+
+const (
+	ConflictFirstByCellID ConflictPolicy = iota
+	ConflictHighestEnergy
+	ConflictRandom
+	ConflictAllFail
+	MaxConflictPolicy
+)
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case ConflictFirstByCellID:
+		return "first"
+	case ConflictHighestEnergy:
+		return "energy"
+	case ConflictRandom:
+		return "random"
+	case ConflictAllFail:
+		return "none"
+	}
+	panic(p)
+}
+
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	for p := ConflictPolicy(0); p < MaxConflictPolicy; p++ {
+		if p.String() == value {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown conflict policy %q", value)
+}
+
+// contender is a cell competing for a tile, order is the cell id or the spawning cell id for births
+type contender struct {
+	cell  *Cell
+	order uint64
+}
+
+// resolveConflict returns the winner of the contenders, nil when all of them fail
+func (w *World) resolveConflict(contenders []contender) *Cell {
+	if len(contenders) == 1 {
+		return contenders[0].cell
+	}
+	// sorted first so the result only depends on the cells and the world random
+	sort.Slice(contenders, func(i, j int) bool {
+		if contenders[i].order != contenders[j].order {
+			return contenders[i].order < contenders[j].order
+		}
+		return contenders[i].cell.direction < contenders[j].cell.direction
+	})
+	switch w.config.ConflictResolution {
+	case ConflictFirstByCellID:
+		return contenders[0].cell
+	case ConflictHighestEnergy:
+		winner := contenders[0].cell
+		for i := range contenders[1:] {
+			if contenders[i+1].cell.GetFromInventory(ItemTypeEnergy) > winner.GetFromInventory(ItemTypeEnergy) {
+				winner = contenders[i+1].cell
+			}
+		}
+		return winner
+	case ConflictRandom:
+		return contenders[w.random.Uint32()%uint32(len(contenders))].cell
+	case ConflictAllFail:
+		return nil
+	}
+	// Validate keeps unknown policies out of the config, the first cell wins otherwise
+	return contenders[0].cell
+}
+
+// sortedPositions orders the contested tiles so the results only depend on the world random
+func sortedPositions(targets map[Position][]contender) []Position {
+	result := make([]Position, 0, len(targets))
+	for pos := range targets {
+		result = append(result, pos)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return positionLess(result[i], result[j])
+	})
+	return result
+}
+
+// positionLess orders the tiles by column and then by row
+func positionLess(a, b Position) bool {
+	return a.X < b.X || a.X == b.X && a.Y < b.Y
+}
+
+func (w *World) emitConflictLost(cell *Cell, pos Position, action string) {
+	w.metrics.ConflictsLost += 1
+	w.events.Emit(ConflictLost{
+		EventHeader: w.header(EventConflictLost), Position: pos, CellType: cell.cellType, Organism: cell.organismID,
+		Genome: cell.genomeID, Action: action,
+	})
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+)
+
+// conflictOutcome is the genome of the cell which took the contested tile, empty when none did, and the events
+type conflictOutcome struct {
+	winner string
+	events []Event
+}
+
+// contend lets a poor cell with the lower id and a rich one with the higher id move or spawn into the tile
+// between them, reversed registers them the other way round as workers and map iteration could
+func contend(t *testing.T, policy ConflictPolicy, birth bool, reversed bool) conflictOutcome {
+	w := NewSeededWorld(WorldSize, 3)
+	config := DefaultConfig()
+	config.ConflictResolution = policy
+	if err := w.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	var result conflictOutcome
+	w.Events().Subscribe(func(e Event) {
+		result.events = append(result.events, e)
+	})
+	target, poorStart, richStart := Position{X: 10, Y: 10}, Position{X: 10, Y: 9}, Position{X: 10, Y: 11}
+	poorGenome := &Genome{id: "poor", genome: make([]uint8, GenomeSize)}
+	richGenome := &Genome{id: "rich", genome: make([]uint8, GenomeSize)}
+	w.AddGenome(poorGenome)
+	w.AddGenome(richGenome)
+	poor := w.AddCell(poorStart, NewCell("poor", CellTypeSprout, Inventory{ItemTypeEnergy: 10}, "a"))
+	poor.direction = DirectionSouth
+	rich := w.AddCell(richStart, NewCell("rich", CellTypeSprout, Inventory{ItemTypeEnergy: 50}, "b"))
+	rich.direction = DirectionNorth
+
+	w.CleanupTurn()
+	if birth {
+		poorChild := NewCell("poor", CellTypeLeaf, Inventory{ItemTypeEnergy: 10}, "a")
+		richChild := NewCell("rich", CellTypeLeaf, Inventory{ItemTypeEnergy: 50}, "b")
+		if reversed {
+			w.RegisterNewCell(rich, richChild, target, richGenome)
+			w.RegisterNewCell(poor, poorChild, target, poorGenome)
+		} else {
+			w.RegisterNewCell(poor, poorChild, target, poorGenome)
+			w.RegisterNewCell(rich, richChild, target, richGenome)
+		}
+		w.CreateNewCells()
+	} else {
+		if reversed {
+			w.RegisterMove(rich)
+			w.RegisterMove(poor)
+		} else {
+			w.RegisterMove(poor)
+			w.RegisterMove(rich)
+		}
+		w.MoveCells()
+	}
+
+	if winner := w.GetCellByPosition(target); winner != nil {
+		result.winner = winner.genomeID
+	}
+	// the parents stay and the losing movers keep their tiles
+	if result.winner != "poor" && w.GetCellByPosition(poorStart) != poor {
+		t.Fatal("the poor cell left its tile without winning")
+	}
+	if result.winner != "rich" && w.GetCellByPosition(richStart) != rich {
+		t.Fatal("the rich cell left its tile without winning")
+	}
+	expectedCells := 2
+	if birth && result.winner != "" {
+		expectedCells = 3
+	}
+	if w.CellCount() != expectedCells {
+		t.Fatalf("%d cells in the world instead of %d", w.CellCount(), expectedCells)
+	}
+	return result
+}
+
+func TestConflictResolution(t *testing.T) {
+	for _, c := range []struct {
+		policy ConflictPolicy
+		// winner is empty when either cell may win
+		winner string
+		lost   int
+	}{
+		{ConflictFirstByCellID, "poor", 1},
+		{ConflictHighestEnergy, "rich", 1},
+		{ConflictRandom, "", 1},
+		{ConflictAllFail, "", 2},
+	} {
+		for _, birth := range []bool{false, true} {
+			action := "move"
+			if birth {
+				action = "birth"
+			}
+			t.Run(fmt.Sprintf("%v %s", c.policy, action), func(t *testing.T) {
+				outcome := contend(t, c.policy, birth, false)
+				if c.winner != "" && outcome.winner != c.winner {
+					t.Fatalf("%q won instead of %q", outcome.winner, c.winner)
+				}
+				if c.policy == ConflictRandom && outcome.winner == "" {
+					t.Fatal("nobody won the random conflict")
+				}
+				if c.policy == ConflictAllFail && outcome.winner != "" {
+					t.Fatalf("%q won although all should fail", outcome.winner)
+				}
+				lost, born := 0, 0
+				for _, e := range outcome.events {
+					switch e := e.(type) {
+					case ConflictLost:
+						lost += 1
+						if e.Genome == outcome.winner || e.Action != action || e.Position != (Position{X: 10, Y: 10}) {
+							t.Fatalf("unexpected %+v", e)
+						}
+					case CellBorn:
+						born += 1
+						if e.Genome != outcome.winner {
+							t.Fatalf("unexpected %+v", e)
+						}
+					}
+				}
+				if lost != c.lost {
+					t.Fatalf("%d conflict lost events instead of %d: %v", lost, c.lost, outcome.events)
+				}
+				if expected := map[bool]int{false: 0, true: 2 - c.lost}[birth]; born != expected {
+					t.Fatalf("%d cell born events instead of %d", born, expected)
+				}
+				reversed := contend(t, c.policy, birth, true)
+				sameEvents := fmt.Sprintf("%+v", reversed.events) == fmt.Sprintf("%+v", outcome.events)
+				if reversed.winner != outcome.winner || !sameEvents {
+					t.Fatalf("the order of registration changed the outcome from %v to %v", outcome, reversed)
+				}
+			})
+		}
+	}
+}
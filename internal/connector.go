@@ -0,0 +1,85 @@
+package internal
+
+import "fmt"
+
+// ConnectorMode decides how a connector treats the neighbours of foreign organisms
+type ConnectorMode uint8
+
+const (
+	// ConnectorClosed only passes resources within its organism
+	ConnectorClosed ConnectorMode = iota
+	// ConnectorShare lets resources flow down the gradient to and from foreign neighbours
+	ConnectorShare
+	// ConnectorVampire pulls resources from foreign neighbours regardless of the gradient
+	ConnectorVampire
+	MaxConnectorMode
+)
+
+func (m ConnectorMode) String() string {
+	switch m {
+	case ConnectorClosed:
+		return "closed"
+	case ConnectorShare:
+		return "share"
+	case ConnectorVampire:
+		return "vampire"
+	}
+	panic(m)
+}
+
+// connectorGenes decodes the genes after the position of the connector, which are the seed distance and sprout
+// mask of the transformation and unused by connectors otherwise. The rates are transfer steps, two bits per item
+func connectorGenes(g *Genome, position uint8) (ConnectorMode, [MaxItemType]int) {
+	mode := ConnectorMode(g.GetGene(position+1) % uint8(MaxConnectorMode))
+	var rates [MaxItemType]int
+	for it := ItemType(0); it < MaxItemType; it++ {
+		rates[it] = int(g.GetGene(position+2)>>(2*it)) & 3
+	}
+	return mode, rates
+}
+
+func (w *World) connectorGenes(c *Cell) (ConnectorMode, [MaxItemType]int) {
+	return connectorGenes(w.GetGenome(c.genomeID), c.genomePosition)
+}
+
+// pullByVampires moves the item from foreign neighbours into the vampire connectors at their rates,
+// it returns the pulled amount
+func (w *World) pullByVampires(it ItemType) int64 {
+	step := itemSpreadStep(it)
+	if step == 0 {
+		return 0
+	}
+	limit := (&CellInventory{}).maxForItemType(it)
+	pulled := int64(0)
+	for _, cell := range w.sortedCells() {
+		if cell.cellType != CellTypeConnector {
+			continue
+		}
+		mode, rates := w.connectorGenes(cell)
+		if mode != ConnectorVampire || rates[it] == 0 {
+			continue
+		}
+		ns := w.Neighbours(w.GetPosition(cell))
+		for k := range ns {
+			victim := w.GetCellByPosition(ns[k])
+			if victim == nil || victim.organismID == cell.organismID {
+				continue
+			}
+			amount := min(int16(rates[it])*step, victim.GetFromInventory(it), limit-cell.GetFromInventory(it))
+			if amount <= 0 {
+				continue
+			}
+			victim.AddToInventory(it, -amount)
+			cell.AddToInventory(it, amount)
+			pulled += int64(amount)
+		}
+	}
+	return pulled
+}
+
+func (g *Genome) disassembleConnector(position uint8) string {
+	mode, rates := connectorGenes(g, position)
+	return fmt.Sprintf(
+		"%s connector, rates %d %d %d", mode, rates[ItemTypeEnergy], rates[ItemTypeWater], rates[ItemTypeOrganic],
+	)
+}
@@ -2,6 +2,21 @@ package internal
 
 const (
 	WorldSize = 100
+	// GenomeSize is the amount of genes, every gene position fits into a byte
+	GenomeSize = 256
+	// SpeciesThreshold allows a few mutations of the species founder
+	SpeciesThreshold = 16
+	// ActivityThreshold separates adaptive genomes from the neutral shadow of short lived mutants
+	ActivityThreshold = 500
+	// InstructionBudget limits the genes a cell executes in a turn when none of them ends it
+	InstructionBudget = 8
+	// RegisterCount is the amount of memory registers of every cell
+	RegisterCount = 4
+	// MorphogenCount is the amount of signal channels spreading within organisms
+	MorphogenCount = 4
+	MaxMorphogen   = 1024
+	// MorphogenDecay is the percentage of every signal lost per turn
+	MorphogenDecay = 10
 
 	MaxEnergy            = int16(1024)
 	EnergyTax            = int16(2)
@@ -25,6 +40,7 @@ const (
 	MaxSunLevel           = 20
 	MaxSeedFlyingDistance = 20
 	EnergyTransferAmount  = 20
+	TransportPasses       = 4
 )
 
 type Direction uint8
@@ -52,6 +68,26 @@ const (
 	MaxCellType
 )
 
+func (ct CellType) String() string {
+	switch ct {
+	case CellTypeLeaf:
+		return "leaf"
+	case CellTypeTrunk:
+		return "trunk"
+	case CellTypeFlower:
+		return "flower"
+	case CellTypeSeed:
+		return "seed"
+	case CellTypeSprout:
+		return "sprout"
+	case CellTypeRoot:
+		return "root"
+	case CellTypeConnector:
+		return "connector"
+	}
+	panic(ct)
+}
+
 func CanMove(cellType CellType) bool {
 	return cellType == CellTypeSeed
 }
@@ -63,6 +99,9 @@ const (
 	CompareEnergyLevel
 	CompareCellType
 	CompareNeighboursCount
+	CompareRegister
+	CompareMorphogen
+	CompareMorphogenGradient
 	MaxConditionType
 )
 
@@ -75,6 +114,10 @@ const (
 	GeneTurnTo
 	GeneMove
 	GeneRotate
+	GeneSetRegister
+	GeneIncrementRegister
+	GeneCopyRegister
+	GeneEmitMorphogen
 	MaxGeneCommand
 )
 
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// relativeDirectionNames follow the sprout mask bits, turning clockwise from the facing
+var relativeDirectionNames = [DirectionMax]string{"ahead", "right", "behind", "left"}
+
+func (r Relation) String() string {
+	switch r {
+	case RelationSameOrganism:
+		return "same organism"
+	case RelationSameGenome:
+		return "same genome"
+	case RelationAnotherOrganism:
+		return "another organism"
+	case RelationAnotherGenome:
+		return "another genome"
+	case RelationAny:
+		return "any"
+	}
+	panic(r)
+}
+
+// Disassemble describes the instruction starting at every gene, jumps may land on any of them
+func (g *Genome) Disassemble() []string {
+	result := make([]string, len(g.genome))
+	for i := range g.genome {
+		result[i] = fmt.Sprintf("%3d: %02x  %s", i, g.genome[i], g.disassembleAt(uint8(i)))
+	}
+	return result
+}
+
+func (g *Genome) disassembleAt(position uint8) string {
+	switch g.extractCommand(g.GetGene(position)) {
+	case GenePass:
+		return "pass"
+	case GeneIf:
+		return g.disassembleIf(position)
+	case GeneGoTo:
+		return fmt.Sprintf("goto %d", g.GetGene(position+1))
+	case GeneTurnTo:
+		ct := CellType(g.GetGene(position+1) % uint8(MaxCellType))
+		target := ct.String()
+		if ct == CellTypeSeed {
+			target = "random type"
+		}
+		if ct == CellTypeConnector {
+			return fmt.Sprintf("turn into %s, next %d", g.disassembleConnector(position+1), position+1)
+		}
+		var sprouts []string
+		for i := uint8(0); i < uint8(DirectionMax); i++ {
+			if g.GetGene(position+3)&(1<<i) != 0 {
+				sprouts = append(sprouts, fmt.Sprintf("%s@%d", relativeDirectionNames[i], g.GetGene(position+4+i)))
+			}
+		}
+		if len(sprouts) == 0 {
+			sprouts = append(sprouts, "none")
+		}
+		return fmt.Sprintf(
+			"turn into %s, sprouts %s, seed distance %d, next %d", target, strings.Join(sprouts, " "),
+			int(g.GetGene(position+2)%MaxSeedFlyingDistance), position+1,
+		)
+	case GeneMove:
+		return fmt.Sprintf("move, next %d", position+1)
+	case GeneRotate:
+		direction := "right"
+		if g.GetGene(position+1)%2 == 1 {
+			direction = "left"
+		}
+		return fmt.Sprintf("rotate %s, next %d", direction, position+1)
+	case GeneSetRegister:
+		return fmt.Sprintf(
+			"set r%d = %d, next %d", g.GetGene(position+1)%RegisterCount, g.GetGene(position+2), position+3,
+		)
+	case GeneIncrementRegister:
+		return fmt.Sprintf("increment r%d, next %d", g.GetGene(position+1)%RegisterCount, position+2)
+	case GeneCopyRegister:
+		return fmt.Sprintf(
+			"copy r%d = facing neighbour r%d, next %d", g.GetGene(position+1)%RegisterCount,
+			g.GetGene(position+2)%RegisterCount, position+3,
+		)
+	case GeneEmitMorphogen:
+		return fmt.Sprintf(
+			"emit %d of signal %d per turn, next %d", g.GetGene(position+2), g.GetGene(position+1)%MorphogenCount,
+			position+3,
+		)
+	}
+	return "pass"
+}
+
+// disassembleIf mirrors executeIf, including the genes shared between the condition and its arguments
+func (g *Genome) disassembleIf(position uint8) string {
+	switch g.extractCondition(g.GetGene(position + 1)) {
+	case CompareNextTwoGenes:
+		first, second := g.GetGene(position+2), g.GetGene(position+3)
+		operator := ">"
+		if g.GetGene(position+4)%2 == 1 {
+			operator = "<="
+		}
+		return fmt.Sprintf("if %d %s %d goto %d else %d", first, operator, second, position+2, position+3)
+	case CompareEnergyLevel:
+		operator := ">="
+		if g.GetGene(position+2)%2 == 0 {
+			operator = "<"
+		}
+		value := (int16(g.GetGene(position+1)) / 255) * MaxEnergy
+		return fmt.Sprintf("if energy %s %d goto %d else %d", operator, value, position+3, position+5)
+	case CompareCellType:
+		operator := "!="
+		if g.GetGene(position+2)%2 == 0 {
+			operator = "=="
+		}
+		value := CellType(g.GetGene(position+1) % uint8(MaxCellType))
+		return fmt.Sprintf("if type %s %s goto %d else %d", operator, value, position+3, position+5)
+	case CompareNeighboursCount:
+		operator := ">="
+		if g.GetGene(position+2)%2 == 0 {
+			operator = "<"
+		}
+		relation := Relation(g.GetGene(position+3) % uint8(MaxRelationType))
+		return fmt.Sprintf(
+			"if neighbours of %s %s %d goto %d else %d", relation, operator, g.GetGene(position+1)%5,
+			position+4, position+6,
+		)
+	case CompareRegister:
+		operator := ">="
+		if g.GetGene(position+4)%2 == 0 {
+			operator = "<"
+		}
+		return fmt.Sprintf(
+			"if r%d %s %d goto %d else %d", g.GetGene(position+2)%RegisterCount, operator, g.GetGene(position+3),
+			position+5, position+7,
+		)
+	case CompareMorphogen:
+		operator := ">="
+		if g.GetGene(position+4)%2 == 0 {
+			operator = "<"
+		}
+		return fmt.Sprintf(
+			"if signal %d %s %d goto %d else %d", g.GetGene(position+2)%MorphogenCount, operator,
+			int16(g.GetGene(position+3))*(MaxMorphogen/256), position+5, position+7,
+		)
+	case CompareMorphogenGradient:
+		return fmt.Sprintf(
+			"if signal %d rises ahead goto %d else %d", g.GetGene(position+2)%MorphogenCount, position+3,
+			position+5,
+		)
+	}
+	return "pass"
+}
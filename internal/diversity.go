@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// DiversitySampleSize limits the genomes compared pairwise for the mean genome distance
+const DiversitySampleSize = 128
+
+// activityTracker accumulates the Bedau-Packard evolutionary activity of every living genome,
+// the activity grows by the amount of cells of the genome every turn
+type activityTracker struct {
+	activity map[string]int
+	births   map[string]int
+	turn     int
+	computed TurnMetrics
+	// random samples the genomes, the world random is left alone so the metrics do not change the simulation
+	random *Random
+	mx     sync.Mutex
+}
+
+func newActivityTracker(seed int64) *activityTracker {
+	return &activityTracker{
+		activity: make(map[string]int), births: make(map[string]int), turn: -1, random: NewRandom(seed),
+	}
+}
+
+// shannonSimpson returns the Shannon entropy and the Gini-Simpson index of the counts
+func shannonSimpson(counts []int) (float64, float64) {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	shannon, simpson := 0.0, 1.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		shannon -= p * math.Log(p)
+		simpson -= p * p
+	}
+	return shannon, simpson
+}
+
+// meanGenomeDistance averages the Hamming distance over the pairs of a sample of the genomes
+func (w *World) meanGenomeDistance(ids []string, random *Random) float64 {
+	if len(ids) > DiversitySampleSize {
+		sample := append([]string(nil), ids...)
+		for i := 0; i < DiversitySampleSize; i++ {
+			j := i + int(random.Uint32()%uint32(len(sample)-i))
+			sample[i], sample[j] = sample[j], sample[i]
+		}
+		ids = sample[:DiversitySampleSize]
+	}
+	if len(ids) < 2 {
+		return 0
+	}
+	genomes := make([]*Genome, len(ids))
+	for i := range ids {
+		genomes[i] = w.GetGenome(ids[i])
+	}
+	total, pairs := 0, 0
+	for i := range genomes {
+		for j := i + 1; j < len(genomes); j++ {
+			total += hammingDistance(genomes[i].genome, genomes[j].genome)
+			pairs += 1
+		}
+	}
+	return float64(total) / float64(pairs)
+}
+
+// diversityMetrics fills the diversity and activity statistics once per turn, genomeCells are
+// the amounts of cells of every living genome and genomeSpecies their species
+func (w *World) diversityMetrics(result *TurnMetrics, genomeCells map[string]int, genomeSpecies map[string]int) {
+	t := w.activity
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.turn == w.turn {
+		copyDiversityMetrics(result, &t.computed)
+		return
+	}
+	t.turn = w.turn
+
+	ids := make([]string, 0, len(genomeCells))
+	genomeCounts := make([]int, 0, len(genomeCells))
+	speciesCells := make(map[int]int)
+	for id, cells := range genomeCells {
+		ids = append(ids, id)
+		genomeCounts = append(genomeCounts, cells)
+		speciesCells[genomeSpecies[id]] += cells
+	}
+	speciesCounts := make([]int, 0, len(speciesCells))
+	for _, cells := range speciesCells {
+		speciesCounts = append(speciesCounts, cells)
+	}
+	// sorted so the sampling only depends on the seed
+	sort.Strings(ids)
+	computed := TurnMetrics{}
+	computed.GenomeShannon, computed.GenomeSimpson = shannonSimpson(genomeCounts)
+	computed.SpeciesShannon, computed.SpeciesSimpson = shannonSimpson(speciesCounts)
+	computed.MeanGenomeDistance = w.meanGenomeDistance(ids, t.random)
+
+	ages := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if _, found := t.births[id]; !found {
+			t.births[id] = w.turn
+		}
+		ages = append(ages, w.turn-t.births[id])
+		previous := t.activity[id]
+		t.activity[id] += genomeCells[id]
+		computed.TotalActivity += int64(t.activity[id])
+		if previous < w.config.ActivityThreshold && t.activity[id] >= w.config.ActivityThreshold {
+			computed.NewActivity += 1
+		}
+	}
+	for id := range t.activity {
+		if _, found := genomeCells[id]; !found {
+			delete(t.activity, id)
+			delete(t.births, id)
+		}
+	}
+	if len(ids) > 0 {
+		computed.MeanActivity = float64(computed.TotalActivity) / float64(len(ids))
+		sort.Ints(ages)
+		total := 0
+		for i := range ages {
+			total += ages[i]
+		}
+		computed.GenomeAgeMean = float64(total) / float64(len(ages))
+		computed.GenomeAgeMedian = ages[len(ages)/2]
+		computed.GenomeAgeP90 = ages[len(ages)*9/10]
+		computed.GenomeAgeMax = ages[len(ages)-1]
+	}
+	t.computed = computed
+	copyDiversityMetrics(result, &computed)
+}
+
+func copyDiversityMetrics(to, from *TurnMetrics) {
+	to.GenomeShannon, to.GenomeSimpson = from.GenomeShannon, from.GenomeSimpson
+	to.SpeciesShannon, to.SpeciesSimpson = from.SpeciesShannon, from.SpeciesSimpson
+	to.MeanGenomeDistance = from.MeanGenomeDistance
+	to.GenomeAgeMean, to.GenomeAgeMedian = from.GenomeAgeMean, from.GenomeAgeMedian
+	to.GenomeAgeP90, to.GenomeAgeMax = from.GenomeAgeP90, from.GenomeAgeMax
+	to.TotalActivity, to.MeanActivity, to.NewActivity = from.TotalActivity, from.MeanActivity, from.NewActivity
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+type cellState struct {
+	cellType  CellType
+	genome    string
+	inventory Inventory
+}
+
+// runWithMetrics steps a seeded world and returns the cells of every turn, the ids are random so the
+// genomes are compared by their content
+func runWithMetrics(t *testing.T, metrics bool) []map[Position]cellState {
+	w := NewSeededWorld(WorldSize, 5)
+	w.SeedWorld(DefaultSeedChance, RandomGenomes{})
+	var turns []map[Position]cellState
+	for i := 0; i < 30; i++ {
+		w.Step()
+		if metrics {
+			if w.Metrics().Genomes <= DiversitySampleSize {
+				t.Fatal("too few genomes to sample them")
+			}
+		}
+		turn := make(map[Position]cellState, w.CellCount())
+		w.cells.forEach(func(cell *Cell, pos Position) {
+			turn[pos] = cellState{cell.cellType, string(w.GetGenome(cell.genomeID).genome), cell.inventory}
+		})
+		turns = append(turns, turn)
+	}
+	return turns
+}
+
+func TestMetricsDoNotChangeTheSimulation(t *testing.T) {
+	if !reflect.DeepEqual(runWithMetrics(t, true), runWithMetrics(t, false)) {
+		t.Fatal("computing the metrics changed the turns of the world")
+	}
+}
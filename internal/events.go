@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+type EventType uint8
+
+const (
+	EventCellBorn EventType = iota
+	EventCellDied
+	EventCellTransformed
+	EventSeedLaunched
+	EventSeedLanded
+	EventSpawnBlocked
+	EventMutationOccurred
+	EventOrganismExtinct
+	EventOrganismSplit
+	EventConflictLost
+	MaxEventType
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCellBorn:
+		return "cell_born"
+	case EventCellDied:
+		return "cell_died"
+	case EventCellTransformed:
+		return "cell_transformed"
+	case EventSeedLaunched:
+		return "seed_launched"
+	case EventSeedLanded:
+		return "seed_landed"
+	case EventSpawnBlocked:
+		return "spawn_blocked"
+	case EventMutationOccurred:
+		return "mutation_occurred"
+	case EventOrganismExtinct:
+		return "organism_extinct"
+	case EventOrganismSplit:
+		return "organism_split"
+	case EventConflictLost:
+		return "conflict_lost"
+	}
+	panic(t)
+}
+
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (ct CellType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ct.String())
+}
+
+func (c DeathCause) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+type EventHeader struct {
+	Turn int       `json:"turn"`
+	Type EventType `json:"type"`
+}
+
+func (h EventHeader) Header() EventHeader {
+	return h
+}
+
+type Event interface {
+	Header() EventHeader
+}
+
+type CellBorn struct {
+	EventHeader
+	Position Position `json:"position"`
+	CellType CellType `json:"cell_type"`
+	Organism string   `json:"organism"`
+	Genome   string   `json:"genome"`
+}
+
+type CellDied struct {
+	EventHeader
+	Position Position   `json:"position"`
+	CellType CellType   `json:"cell_type"`
+	Organism string     `json:"organism"`
+	Genome   string     `json:"genome"`
+	Cause    DeathCause `json:"cause"`
+	Age      int16      `json:"age"`
+}
+
+type CellTransformed struct {
+	EventHeader
+	Position Position `json:"position"`
+	From     CellType `json:"from"`
+	To       CellType `json:"to"`
+	Organism string   `json:"organism"`
+	Genome   string   `json:"genome"`
+}
+
+type SeedLaunched struct {
+	EventHeader
+	Position    Position `json:"position"`
+	Genome      string   `json:"genome"`
+	FlyingTimer int      `json:"flying_timer"`
+}
+
+type SeedLanded struct {
+	EventHeader
+	Position Position `json:"position"`
+	Genome   string   `json:"genome"`
+}
+
+type SpawnBlocked struct {
+	EventHeader
+	Position Position `json:"position"`
+	CellType CellType `json:"cell_type"`
+	Organism string   `json:"organism"`
+	Genome   string   `json:"genome"`
+	Occupant CellType `json:"occupant"`
+}
+
+type MutationOccurred struct {
+	EventHeader
+	Parent       string `json:"parent"`
+	Child        string `json:"child"`
+	ChangedGenes int    `json:"changed_genes"`
+}
+
+type OrganismExtinct struct {
+	EventHeader
+	Organism  string `json:"organism"`
+	Founder   string `json:"founder"`
+	BirthTurn int    `json:"birth_turn"`
+	PeakSize  int    `json:"peak_size"`
+}
+
+// OrganismSplit is emitted for every fragment detached into a new organism
+type OrganismSplit struct {
+	EventHeader
+	Organism string `json:"organism"`
+	Fragment string `json:"fragment"`
+	Size     int    `json:"size"`
+}
+
+// ConflictLost is emitted for every cell which lost a tile to another birth or move, Action is birth or move
+type ConflictLost struct {
+	EventHeader
+	Position Position `json:"position"`
+	CellType CellType `json:"cell_type"`
+	Organism string   `json:"organism"`
+	Genome   string   `json:"genome"`
+	Action   string   `json:"action"`
+}
+
+type EventBus struct {
+	mx          sync.Mutex
+	subscribers []func(Event)
+}
+
+// Subscribe registers a handler called synchronously for every event, from any goroutine
+func (b *EventBus) Subscribe(handler func(Event)) {
+	b.mx.Lock()
+	b.subscribers = append(b.subscribers, handler)
+	b.mx.Unlock()
+}
+
+// Emit calls the handlers outside of the lock, so a handler may subscribe or emit events itself
+func (b *EventBus) Emit(e Event) {
+	b.mx.Lock()
+	// subscribers are only appended, the handlers seen under the lock do not change afterwards
+	subscribers := b.subscribers
+	b.mx.Unlock()
+	for i := range subscribers {
+		subscribers[i](e)
+	}
+}
+
+// JSONLinesSink writes every event as a single buffered JSON object per line and keeps the first error
+type JSONLinesSink struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error
+	mx      sync.Mutex
+}
+
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	writer := bufio.NewWriter(w)
+	return &JSONLinesSink{writer: writer, encoder: json.NewEncoder(writer)}
+}
+
+func (s *JSONLinesSink) Handle(e Event) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.err == nil {
+		s.err = s.encoder.Encode(e)
+	}
+}
+
+func (s *JSONLinesSink) Flush() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return s.writer.Flush()
+}
+
+func (w *World) Events() *EventBus {
+	return &w.events
+}
+
+func (w *World) header(t EventType) EventHeader {
+	return EventHeader{Turn: w.turn, Type: t}
+}
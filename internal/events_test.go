@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// uuids differ between runs, the rest of the event log has to be the same
+var uuidPattern = regexp.MustCompile(`"[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}"`)
+
+func recordEvents(t *testing.T, scheduler SchedulerType, workers int) []byte {
+	w := NewSeededWorld(WorldSize, 7)
+	config := w.Config()
+	config.Scheduler = scheduler
+	config.Workers = workers
+	if err := w.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	sink := NewJSONLinesSink(&log)
+	w.Events().Subscribe(sink.Handle)
+	w.SeedWorld(DefaultSeedChance, RandomGenomes{})
+	for i := 0; i < 40; i++ {
+		w.Step()
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return uuidPattern.ReplaceAll(log.Bytes(), []byte(`"id"`))
+}
+
+func TestEventOrderIsDeterministic(t *testing.T) {
+	expected := recordEvents(t, SchedulerChunks, 1)
+	if len(expected) == 0 {
+		t.Fatal("no events were emitted")
+	}
+	for _, c := range []struct {
+		scheduler SchedulerType
+		workers   int
+	}{{SchedulerChunks, 1}, {SchedulerChunks, 4}, {SchedulerPerCell, 0}} {
+		if log := recordEvents(t, c.scheduler, c.workers); !bytes.Equal(log, expected) {
+			t.Fatalf("%v scheduler with %d workers emitted a different event log", c.scheduler, c.workers)
+		}
+	}
+}
+
+func TestSeedsLandWhenTheyAreGrounded(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	var landed []Position
+	w.Events().Subscribe(func(e Event) {
+		if e, ok := e.(SeedLanded); ok {
+			landed = append(landed, e.Position)
+		}
+	})
+	genome := &Genome{id: "g", genome: make([]uint8, GenomeSize)}
+	w.PlantSeed(Position{X: 1, Y: 1}, genome)
+	if len(landed) != 1 || landed[0] != (Position{X: 1, Y: 1}) {
+		t.Fatalf("the planted seed landed at %v", landed)
+	}
+
+	flower := w.AddCell(Position{X: 10, Y: 10}, NewCell("g", CellTypeFlower, Inventory{}, "o"))
+	w.CleanupTurn()
+	grounded := NewCell("g", CellTypeSeed, Inventory{ItemTypeEnergy: 10}, "a")
+	flying := NewCell("g", CellTypeSeed, Inventory{ItemTypeEnergy: 10}, "b")
+	flying.seedFlyingTimer = 1
+	flying.direction = DirectionEast
+	w.RegisterNewCell(flower, grounded, Position{X: 10, Y: 11}, genome)
+	w.RegisterNewCell(flower, flying, Position{X: 11, Y: 10}, genome)
+	w.CreateNewCells()
+	if len(landed) != 2 || landed[1] != (Position{X: 10, Y: 11}) {
+		t.Fatalf("the seed without a flight landed at %v", landed)
+	}
+
+	w.CleanupTurn()
+	w.ExecuteCellGenomes()
+	w.MoveCells()
+	if len(landed) != 3 || landed[2] != (Position{X: 12, Y: 10}) {
+		t.Fatalf("the flying seed landed at %v", landed)
+	}
+}
+
+func TestHandlersMaySubscribeAndEmit(t *testing.T) {
+	var bus EventBus
+	var received []EventType
+	bus.Subscribe(func(e Event) {
+		received = append(received, e.Header().Type)
+		if e.Header().Type == EventCellBorn {
+			bus.Subscribe(func(e Event) {
+				received = append(received, e.Header().Type)
+			})
+			bus.Emit(SeedLanded{EventHeader: EventHeader{Type: EventSeedLanded}})
+		}
+	})
+	bus.Emit(CellBorn{EventHeader: EventHeader{Type: EventCellBorn}})
+	expected := []EventType{EventCellBorn, EventSeedLanded, EventSeedLanded}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("received %v instead of %v", received, expected)
+	}
+}
@@ -1,7 +1,7 @@
 package internal
 
 import (
-	"math/rand"
+	"fmt"
 	"sync"
 
 	"github.com/google/uuid"
@@ -17,7 +17,8 @@ func (g *Genome) extractCommand(gene uint8) GeneCommand {
 	return GeneCommand(gene) % MaxGeneCommand
 }
 
-func (g *Genome) ExecutePosition(position uint8, world *World) Action {
+// ExecutePosition decides the action of the gene, the random source belongs to the executing cell
+func (g *Genome) ExecutePosition(position uint8, world *World, random *Random) Action {
 	gene := g.genome[position]
 	geneCommand := g.extractCommand(gene)
 	switch geneCommand {
@@ -28,11 +29,19 @@ func (g *Genome) ExecutePosition(position uint8, world *World) Action {
 	case GeneGoTo:
 		return g.executeGoTo(position)
 	case GeneTurnTo:
-		return g.executeTurnTo(position)
+		return g.executeTurnTo(position, random)
 	case GeneMove:
 		return g.executeMove(position)
 	case GeneRotate:
 		return g.executeRotate(position)
+	case GeneSetRegister:
+		return NewActionSetRegister(g.GetGene(position+1)%RegisterCount, g.GetGene(position+2), position+3)
+	case GeneIncrementRegister:
+		return NewActionIncrementRegister(g.GetGene(position+1)%RegisterCount, position+2)
+	case GeneCopyRegister:
+		return NewActionCopyRegister(g.GetGene(position+1)%RegisterCount, g.GetGene(position+2)%RegisterCount, position+3)
+	case GeneEmitMorphogen:
+		return NewActionEmitMorphogen(g.GetGene(position+1)%MorphogenCount, g.GetGene(position+2), position+3)
 	}
 	return NewActionDoNothing(position + 1)
 }
@@ -86,20 +95,48 @@ func (g *Genome) executeIf(position uint8) Action {
 		positionIfTrue := position + 4
 		positionIfFalse := position + 6
 		comp := func(c *Cell, w *World) bool {
-			ns := w.GetPosition(c).Neighbours()
+			ns := w.Neighbours(w.GetPosition(c))
 			nsCount := 0
 			for i := range ns {
-				anotherCell := w.GetCellByPosition(ns[i])
-				if anotherCell == nil {
-					continue
-				}
-				if w.Occupied(ns[i]) && relationMatch(c, anotherCell, relation) {
+				anotherCell, found := w.view.at(ns[i])
+				if found && relationMatch(c, anotherCell.cell, relation) {
 					nsCount += 1
 				}
 			}
 			return less && nsCount < value || !less && nsCount >= value
 		}
 		return NewActionCompareForCell(comp, positionIfTrue, positionIfFalse)
+	case CompareRegister:
+		register := g.GetGene(position+2) % RegisterCount
+		value := g.GetGene(position + 3)
+		less := g.GetGene(position+4)%2 == 0
+		positionIfTrue := position + 5
+		positionIfFalse := position + 7
+		comp := func(c *Cell, w *World) bool {
+			return less && c.registers[register] < value || !less && c.registers[register] >= value
+		}
+		return NewActionCompareForCell(comp, positionIfTrue, positionIfFalse)
+	case CompareMorphogen:
+		channel := g.GetGene(position+2) % MorphogenCount
+		value := int16(g.GetGene(position+3)) * (MaxMorphogen / 256)
+		less := g.GetGene(position+4)%2 == 0
+		positionIfTrue := position + 5
+		positionIfFalse := position + 7
+		comp := func(c *Cell, w *World) bool {
+			return less && c.morphogens[channel] < value || !less && c.morphogens[channel] >= value
+		}
+		return NewActionCompareForCell(comp, positionIfTrue, positionIfFalse)
+	case CompareMorphogenGradient:
+		channel := g.GetGene(position+2) % MorphogenCount
+		positionIfTrue := position + 3
+		positionIfFalse := position + 5
+		comp := func(c *Cell, w *World) bool {
+			// the signals only change between the parallel phases, so the neighbour is read directly
+			neighbour := w.GetCellByPosition(w.MovedByDirection(w.GetPosition(c), c.direction))
+			return neighbour != nil && neighbour.organismID == c.organismID &&
+				neighbour.morphogens[channel] > c.morphogens[channel]
+		}
+		return NewActionCompareForCell(comp, positionIfTrue, positionIfFalse)
 	}
 
 	return NewActionDoNothing(position + 1)
@@ -114,17 +151,24 @@ func (g *Genome) extractCondition(u uint8) ConditionType {
 	return ConditionType(u % uint8(MaxConditionType))
 }
 
-func (g *Genome) executeTurnTo(position uint8) Action {
+func (g *Genome) executeTurnTo(position uint8, random *Random) Action {
 	ct := CellType(g.GetGene(position+1) % uint8(MaxCellType))
 	newPosition := position + 1
 	if ct == CellTypeSeed {
-		ct = CellType(rand.Uint32() % uint32(MaxCellType))
+		ct = CellType(random.Uint32() % uint32(MaxCellType))
 		if ct == CellTypeSeed {
 			ct = (ct + 1) % MaxCellType
 		}
 	}
-	sproutsAmount := g.GetGene(position+1)%4 + 1
-	return NewActionChangeCellType(ct, newPosition, sproutsAmount, int(g.GetGene(position+2)%MaxSeedFlyingDistance))
+	// the bits of the mask are the directions relative to the facing, each sprout has its own start address
+	var sproutPositions [DirectionMax]uint8
+	for i := range sproutPositions {
+		sproutPositions[i] = g.GetGene(position + 4 + uint8(i))
+	}
+	return NewActionChangeCellType(
+		ct, newPosition, g.GetGene(position+3)%(1<<DirectionMax), sproutPositions,
+		int(g.GetGene(position+2)%MaxSeedFlyingDistance),
+	)
 }
 
 func (g *Genome) extractTurningTarget(u uint8) CellType {
@@ -135,6 +179,15 @@ func (g *Genome) GetID() string {
 	return g.id
 }
 
+func (g *Genome) GetParentID() string {
+	return g.parentID
+}
+
+// GetGenes returns a copy of the raw genes
+func (g *Genome) GetGenes() []uint8 {
+	return append([]uint8(nil), g.genome...)
+}
+
 func (g *Genome) executeMove(position uint8) Action {
 	return NewActionMove(position + 1)
 }
@@ -152,34 +205,62 @@ func (g *Genome) GetGene(position uint8) uint8 {
 	return g.genome[position]
 }
 
-func (g *Genome) Copy(w *World) *Genome {
-	childGenome := NewGenome(g)
+// Copy mutates the genome for a child of the parent cell, using its random source and reporting as the parent
+func (g *Genome) Copy(w *World, parent *Cell) *Genome {
+	childGenome := newMutatedGenome(g, w.config.MutationChance, parent.random)
 	if childGenome.id != g.id {
 		w.GenomeStorage.AddGenome(childGenome)
+		changedGenes := 0
+		for i := range g.genome {
+			if g.genome[i] != childGenome.genome[i] {
+				changedGenes += 1
+			}
+		}
+		w.emitLater(parent, MutationOccurred{
+			EventHeader: w.header(EventMutationOccurred), Parent: g.id, Child: childGenome.id,
+			ChangedGenes: changedGenes,
+		})
 	}
 	return childGenome
 }
 
 func NewGenome(parentGenome *Genome) *Genome {
-	g := Genome{id: uuid.NewString(), genome: make([]uint8, 256), parentID: ""}
+	return newMutatedGenome(parentGenome, MutationChance, defaultRandom)
+}
+
+// NewGenomeFromGenes creates a parentless genome from saved genes
+func NewGenomeFromGenes(genes []uint8) (*Genome, error) {
+	if len(genes) != GenomeSize {
+		return nil, fmt.Errorf("genome has %d genes instead of %d", len(genes), GenomeSize)
+	}
+	return &Genome{id: uuid.NewString(), genome: append([]uint8(nil), genes...)}, nil
+}
+
+// NewRandomGenome creates a genome from random bytes of the given source
+func NewRandomGenome(random *Random) *Genome {
+	return newMutatedGenome(nil, 0, random)
+}
+
+func newMutatedGenome(parentGenome *Genome, mutationChance float32, random *Random) *Genome {
+	g := Genome{id: uuid.NewString(), genome: make([]uint8, GenomeSize), parentID: ""}
 	if parentGenome == nil {
 		for i := range g.genome {
-			g.genome[i] = uint8(rand.Uint32())
+			g.genome[i] = uint8(random.Uint32())
 		}
 		return &g
 	}
 
 	changes := false
 	for i := range g.genome {
-		if rand.Float32() < MutationChance {
-			g.genome[i] = uint8(rand.Uint32())
+		if random.Float32() < mutationChance {
+			g.genome[i] = uint8(random.Uint32())
 			changes = true
 		} else {
 			g.genome[i] = parentGenome.genome[i]
 		}
 	}
 	if changes {
-		g.parentID = parentGenome.parentID
+		g.parentID = parentGenome.id
 	} else {
 		return parentGenome
 	}
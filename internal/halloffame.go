@@ -0,0 +1,303 @@
+package internal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Fitness ranks the genomes of the hall of fame
+type Fitness uint8
+
+const (
+	FitnessOrganismSize Fitness = iota
+	FitnessSeedsProduced
+	FitnessLineageLongevity
+	MaxFitness
+)
+
+func (f Fitness) String() string {
+	switch f {
+	case FitnessOrganismSize:
+		return "size"
+	case FitnessSeedsProduced:
+		return "seeds"
+	case FitnessLineageLongevity:
+		return "lineage"
+	}
+	panic(f)
+}
+
+func ParseFitness(value string) (Fitness, error) {
+	for f := Fitness(0); f < MaxFitness; f++ {
+		if f.String() == value {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown fitness %q", value)
+}
+
+func (f Fitness) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *Fitness) UnmarshalText(text []byte) error {
+	var err error
+	*f, err = ParseFitness(string(text))
+	return err
+}
+
+// HallOfFameEntry holds the achievements of a single genome, LineageTurns counts the turns during which
+// the genome or any of its descendants was alive
+type HallOfFameEntry struct {
+	ID              string
+	ParentID        string
+	Genes           string
+	MaxOrganismSize int
+	SeedsProduced   int
+	LineageTurns    int
+
+	observedTurn int
+}
+
+func (e *HallOfFameEntry) Score(f Fitness) int {
+	switch f {
+	case FitnessOrganismSize:
+		return e.MaxOrganismSize
+	case FitnessSeedsProduced:
+		return e.SeedsProduced
+	case FitnessLineageLongevity:
+		return e.LineageTurns
+	}
+	panic(f)
+}
+
+func (e *HallOfFameEntry) Genome() (*Genome, error) {
+	genes, err := hex.DecodeString(e.Genes)
+	if err != nil {
+		return nil, err
+	}
+	genome, err := NewGenomeFromGenes(genes)
+	if err != nil {
+		return nil, err
+	}
+	// the identity is kept so an imported genome continues its own record
+	genome.id, genome.parentID = e.ID, e.ParentID
+	return genome, nil
+}
+
+type hallOfFameFile struct {
+	Fitness Fitness
+	Entries []HallOfFameEntry
+}
+
+// HallOfFame archives the best genomes of a run by the chosen fitness, it also provides them as seeds
+type HallOfFame struct {
+	fitness  Fitness
+	capacity int
+	archive  []*HallOfFameEntry
+	stats    map[string]*HallOfFameEntry
+	genomes  map[string]*Genome
+	mx       sync.Mutex
+}
+
+func NewHallOfFame(fitness Fitness, capacity int) *HallOfFame {
+	return &HallOfFame{
+		fitness: fitness, capacity: max(1, capacity), stats: make(map[string]*HallOfFameEntry),
+		genomes: make(map[string]*Genome),
+	}
+}
+
+// LoadHallOfFame continues an archive saved by an earlier run, zero capacity keeps every saved genome
+func LoadHallOfFame(r io.Reader, capacity int) (*HallOfFame, error) {
+	file := hallOfFameFile{}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if capacity == 0 {
+		capacity = len(file.Entries)
+	}
+	h := NewHallOfFame(file.Fitness, capacity)
+	for i := range file.Entries {
+		entry := file.Entries[i]
+		genome, err := entry.Genome()
+		if err != nil {
+			return nil, fmt.Errorf("hall of fame genome %s: %w", entry.ID, err)
+		}
+		h.stats[entry.ID] = &entry
+		h.genomes[entry.ID] = genome
+		h.consider(&entry)
+	}
+	return h, nil
+}
+
+func (h *HallOfFame) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(hallOfFameFile{Fitness: h.fitness, Entries: h.Entries()})
+}
+
+func (h *HallOfFame) Fitness() Fitness {
+	return h.fitness
+}
+
+// SetFitness ranks the archived genomes and the ones of the living lineages again
+func (h *HallOfFame) SetFitness(fitness Fitness) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	h.fitness = fitness
+	h.archive = nil
+	for _, entry := range h.stats {
+		h.consider(entry)
+	}
+}
+
+// Entries returns a copy of the archive, the best genome first
+func (h *HallOfFame) Entries() []HallOfFameEntry {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	result := make([]HallOfFameEntry, len(h.archive))
+	for i := range h.archive {
+		result[i] = *h.archive[i]
+	}
+	return result
+}
+
+// Attach starts counting the seeds produced in the world, Observe still has to be called after every turn
+func (h *HallOfFame) Attach(w *World) {
+	w.events.Subscribe(func(e Event) {
+		launched, ok := e.(SeedLaunched)
+		if !ok {
+			return
+		}
+		h.mx.Lock()
+		defer h.mx.Unlock()
+		// a seed with a mutated genome is credited to the parent which produced it
+		entry := h.stats[launched.Genome]
+		if entry == nil {
+			if genome := w.GetGenome(launched.Genome); genome != nil {
+				entry = h.stats[genome.parentID]
+			}
+		}
+		if entry != nil {
+			entry.SeedsProduced += 1
+			h.consider(entry)
+		}
+	})
+}
+
+func (h *HallOfFame) entry(w *World, id string) *HallOfFameEntry {
+	if entry, found := h.stats[id]; found {
+		return entry
+	}
+	genome := w.GetGenome(id)
+	if genome == nil {
+		return nil
+	}
+	entry := &HallOfFameEntry{ID: id, ParentID: genome.parentID, Genes: hex.EncodeToString(genome.genome)}
+	h.stats[id] = entry
+	return entry
+}
+
+// Observe updates the organism sizes and lineages from the living cells
+func (h *HallOfFame) Observe(w *World) {
+	organismSizes := make(map[string]int)
+	organismGenomes := make(map[string]map[string]bool)
+	w.cells.forEach(func(c *Cell, _ Position) {
+		organismSizes[c.organismID] += 1
+		if organismGenomes[c.organismID] == nil {
+			organismGenomes[c.organismID] = make(map[string]bool)
+		}
+		organismGenomes[c.organismID][c.genomeID] = true
+	})
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	for organism, genomes := range organismGenomes {
+		for id := range genomes {
+			entry := h.entry(w, id)
+			if entry == nil {
+				continue
+			}
+			if organismSizes[organism] > entry.MaxOrganismSize {
+				entry.MaxOrganismSize = organismSizes[organism]
+				h.consider(entry)
+			}
+			// ancestors observed during this turn already had their own ancestors observed
+			for entry != nil && entry.observedTurn != w.turn {
+				entry.observedTurn = w.turn
+				entry.LineageTurns += 1
+				h.consider(entry)
+				if entry.ParentID == "" {
+					break
+				}
+				entry = h.entry(w, entry.ParentID)
+			}
+		}
+	}
+	h.forgetDead(w.turn)
+}
+
+// forgetDead drops the records of the genomes without living cells or descendants which did not make it into
+// the archive, the mutex is expected to be held
+func (h *HallOfFame) forgetDead(turn int) {
+	archived := make(map[*HallOfFameEntry]bool, len(h.archive))
+	for _, entry := range h.archive {
+		archived[entry] = true
+	}
+	for id, entry := range h.stats {
+		if entry.observedTurn != turn && !archived[entry] {
+			delete(h.stats, id)
+			delete(h.genomes, id)
+		}
+	}
+}
+
+// consider keeps the entry in the archive when it is good enough, the mutex is expected to be held
+func (h *HallOfFame) consider(entry *HallOfFameEntry) {
+	index := -1
+	for i := range h.archive {
+		if h.archive[i] == entry {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		if len(h.archive) >= h.capacity {
+			worst := h.archive[len(h.archive)-1]
+			if worst.Score(h.fitness) >= entry.Score(h.fitness) {
+				return
+			}
+			h.archive = h.archive[:len(h.archive)-1]
+		}
+		h.archive = append(h.archive, entry)
+	}
+	sort.SliceStable(h.archive, func(i, j int) bool {
+		return h.archive[i].Score(h.fitness) > h.archive[j].Score(h.fitness)
+	})
+}
+
+// NextGenome picks a random archived genome, adding it to the world
+func (h *HallOfFame) NextGenome(w *World) *Genome {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if len(h.archive) == 0 {
+		return nil
+	}
+	entry := h.archive[w.random.Uint32()%uint32(len(h.archive))]
+	genome := h.genomes[entry.ID]
+	if genome == nil {
+		genome = w.GetGenome(entry.ID)
+		if genome == nil {
+			var err error
+			if genome, err = entry.Genome(); err != nil {
+				panic(err)
+			}
+		}
+		h.genomes[entry.ID] = genome
+	}
+	return genome
+}
@@ -0,0 +1,33 @@
+package internal
+
+import "testing"
+
+func TestHallOfFameForgetsDeadGenomesOutsideTheArchive(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	h := NewHallOfFame(FitnessOrganismSize, 1)
+	var cells []*Cell
+	for _, c := range []struct {
+		genome string
+		pos    Position
+	}{{"large", Position{X: 1, Y: 1}}, {"large", Position{X: 2, Y: 1}}, {"small", Position{X: 9, Y: 9}}} {
+		w.AddGenome(&Genome{id: c.genome, genome: make([]uint8, GenomeSize)})
+		cells = append(cells, w.AddCell(c.pos, NewCell(c.genome, CellTypeLeaf, Inventory{}, c.genome)))
+	}
+	w.CleanupTurn()
+	h.Observe(w)
+	if len(h.stats) != 2 {
+		t.Fatalf("%d genomes observed instead of 2", len(h.stats))
+	}
+
+	for _, cell := range cells {
+		w.killCell(cell, DeathCauseEnergy)
+	}
+	w.CleanupTurn()
+	h.Observe(w)
+	if len(h.stats) != 1 || h.stats["large"] == nil {
+		t.Fatalf("kept the records of %v instead of the archived genome", h.stats)
+	}
+	if entries := h.Entries(); len(entries) != 1 || entries[0].ID != "large" || entries[0].MaxOrganismSize != 2 {
+		t.Fatalf("unexpected archive %v", entries)
+	}
+}
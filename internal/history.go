@@ -0,0 +1,263 @@
+package internal
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+type historyCell struct {
+	Position      Position
+	CellType      CellType
+	Energy, Water int16
+	Organism      uint32
+	Genome        uint32
+	Species       int
+}
+
+type historyFrame struct {
+	Turn     int
+	Keyframe bool
+	// Strings are interned since the previous frame with their ids, only filled for the recorded file. The ids of
+	// strings no longer used by any kept frame are given to new strings
+	Strings   []string
+	StringIDs []uint32
+	// Cells holds every cell for keyframes and only the changed ones for deltas
+	Cells   []historyCell
+	Removed []Position
+	Metrics TurnMetrics
+}
+
+// historySegment is a keyframe followed by the deltas relative to it
+type historySegment []historyFrame
+
+// History is a ring buffer of per-turn world deltas with periodic keyframes
+type History struct {
+	mx sync.Mutex
+
+	capacity         int
+	keyframeInterval int
+	segments         []historySegment
+	length           int
+
+	strings   []string
+	stringIDs map[string]uint32
+	// references counts the cells of the kept frames using every string, unused ids are reused
+	references   []int
+	freeIDs      []uint32
+	newStrings   []string
+	newStringIDs []uint32
+
+	last   WorldExport
+	cursor WorldExport
+	sink   *gob.Encoder
+}
+
+// NewHistory keeps at least capacity turns, optionally writing every frame to the sink
+func NewHistory(capacity, keyframeInterval int, sink io.Writer) *History {
+	h := &History{
+		capacity: capacity, keyframeInterval: max(1, keyframeInterval), stringIDs: make(map[string]uint32),
+		cursor: NewWorldExport(),
+	}
+	if sink != nil {
+		h.sink = gob.NewEncoder(sink)
+	}
+	return h
+}
+
+// LoadHistory reads a file written by a History sink
+func LoadHistory(r io.Reader) (*History, error) {
+	h := NewHistory(0, 1, nil)
+	decoder := gob.NewDecoder(r)
+	// fileStrings are the strings by their ids in the file, which may differ from the ids of the history
+	var fileStrings []string
+	for {
+		var frame historyFrame
+		err := decoder.Decode(&frame)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(frame.StringIDs) != len(frame.Strings) {
+			return nil, fmt.Errorf(
+				"history turn %d has %d strings for %d string ids", frame.Turn, len(frame.Strings), len(frame.StringIDs),
+			)
+		}
+		for i := range frame.Strings {
+			id := frame.StringIDs[i]
+			for int(id) >= len(fileStrings) {
+				fileStrings = append(fileStrings, "")
+			}
+			fileStrings[id] = frame.Strings[i]
+		}
+		frame.Strings, frame.StringIDs = nil, nil
+		for i := range frame.Cells {
+			frame.Cells[i].Organism = h.intern(fileStrings[frame.Cells[i].Organism])
+			frame.Cells[i].Genome = h.intern(fileStrings[frame.Cells[i].Genome])
+		}
+		if frame.Keyframe || len(h.segments) == 0 {
+			h.segments = append(h.segments, historySegment{})
+		}
+		h.segments[len(h.segments)-1] = append(h.segments[len(h.segments)-1], frame)
+		h.length += 1
+	}
+	h.newStrings, h.newStringIDs = nil, nil
+	h.capacity = h.length
+	return h, nil
+}
+
+// intern returns the id of the string for a cell of a kept frame
+func (h *History) intern(s string) uint32 {
+	if id, found := h.stringIDs[s]; found {
+		h.references[id] += 1
+		return id
+	}
+	var id uint32
+	if len(h.freeIDs) > 0 {
+		id = h.freeIDs[len(h.freeIDs)-1]
+		h.freeIDs = h.freeIDs[:len(h.freeIDs)-1]
+		h.strings[id] = s
+		h.references[id] = 1
+	} else {
+		id = uint32(len(h.strings))
+		h.strings = append(h.strings, s)
+		h.references = append(h.references, 1)
+	}
+	h.stringIDs[s] = id
+	h.newStrings = append(h.newStrings, s)
+	h.newStringIDs = append(h.newStringIDs, id)
+	return id
+}
+
+// release forgets the strings of the evicted segment once no kept frame uses them
+func (h *History) release(segment historySegment) {
+	for i := range segment {
+		for _, c := range segment[i].Cells {
+			h.dereference(c.Organism)
+			h.dereference(c.Genome)
+		}
+	}
+}
+
+func (h *History) dereference(id uint32) {
+	h.references[id] -= 1
+	if h.references[id] == 0 {
+		delete(h.stringIDs, h.strings[id])
+		h.strings[id] = ""
+		h.freeIDs = append(h.freeIDs, id)
+	}
+}
+
+func (h *History) toHistoryCell(e *WorldExport, pos Position) historyCell {
+	return historyCell{
+		Position: pos, CellType: e.cellTypes[pos], Energy: e.energy[pos], Water: e.water[pos],
+		Organism: h.intern(e.organisms[pos]), Genome: h.intern(e.genomes[pos]), Species: e.species[pos],
+	}
+}
+
+func (h *History) Record(e WorldExport) error {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	keyframe := len(h.segments) == 0 || len(h.segments[len(h.segments)-1]) >= h.keyframeInterval
+	frame := historyFrame{Turn: e.turn, Keyframe: keyframe, Metrics: e.metrics}
+	for pos := range e.cellTypes {
+		if !keyframe && !h.last.changedAt(&e, pos) {
+			continue
+		}
+		frame.Cells = append(frame.Cells, h.toHistoryCell(&e, pos))
+	}
+	if !keyframe {
+		for pos := range h.last.cellTypes {
+			if _, found := e.cellTypes[pos]; !found {
+				frame.Removed = append(frame.Removed, pos)
+			}
+		}
+	}
+	h.last = e
+
+	if h.sink != nil {
+		frame.Strings, frame.StringIDs = h.newStrings, h.newStringIDs
+		if err := h.sink.Encode(&frame); err != nil {
+			return err
+		}
+		frame.Strings, frame.StringIDs = nil, nil
+	}
+	h.newStrings, h.newStringIDs = nil, nil
+
+	if keyframe {
+		h.segments = append(h.segments, historySegment{})
+	}
+	h.segments[len(h.segments)-1] = append(h.segments[len(h.segments)-1], frame)
+	h.length += 1
+	for h.length > h.capacity && len(h.segments) > 1 {
+		h.length -= len(h.segments[0])
+		h.release(h.segments[0])
+		h.segments = h.segments[1:]
+	}
+	return nil
+}
+
+// Turns returns the range of turns available for rewinding
+func (h *History) Turns() (first, last int) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if len(h.segments) == 0 {
+		return 0, 0
+	}
+	lastSegment := h.segments[len(h.segments)-1]
+	return h.segments[0][0].Turn, lastSegment[len(lastSegment)-1].Turn
+}
+
+// At replays the world state for the turn from the nearest keyframe
+func (h *History) At(turn int) (WorldExport, bool) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if h.cursor.turn == turn && len(h.cursor.cellTypes) > 0 {
+		return h.cursor, true
+	}
+	for s := range h.segments {
+		segment := h.segments[s]
+		if turn < segment[0].Turn || turn > segment[len(segment)-1].Turn {
+			continue
+		}
+		current := NewWorldExport()
+		fromCursor := len(h.cursor.cellTypes) > 0 && h.cursor.turn >= segment[0].Turn && h.cursor.turn < turn
+		if fromCursor {
+			current = h.cursor.clone()
+		}
+		for i := range segment {
+			frame := &segment[i]
+			if frame.Turn > turn {
+				break
+			}
+			if fromCursor && frame.Turn <= current.turn {
+				continue
+			}
+			h.apply(&current, frame)
+		}
+		h.cursor = current
+		return current, true
+	}
+	return NewWorldExport(), false
+}
+
+func (h *History) apply(e *WorldExport, frame *historyFrame) {
+	for i := range frame.Removed {
+		e.remove(frame.Removed[i])
+	}
+	for i := range frame.Cells {
+		c := frame.Cells[i]
+		e.cellTypes[c.Position] = c.CellType
+		e.energy[c.Position] = c.Energy
+		e.water[c.Position] = c.Water
+		e.organisms[c.Position] = h.strings[c.Organism]
+		e.genomes[c.Position] = h.strings[c.Genome]
+		e.species[c.Position] = c.Species
+	}
+	e.turn = frame.Turn
+	e.metrics = frame.Metrics
+}
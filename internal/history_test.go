@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"testing"
+)
+
+// historyExport has a long living cell and a cell of a new organism every turn
+func historyExport(turn int) WorldExport {
+	e := NewWorldExport()
+	e.turn = turn
+	for _, c := range []struct {
+		pos      Position
+		organism string
+	}{{Position{X: 1, Y: 1}, "old"}, {Position{X: 2, Y: 2}, fmt.Sprintf("organism %d", turn)}} {
+		e.cellTypes[c.pos] = CellTypeLeaf
+		e.organisms[c.pos] = c.organism
+		e.genomes[c.pos] = "genome " + c.organism
+	}
+	return e
+}
+
+func TestHistoryForgetsTheStringsOfEvictedTurns(t *testing.T) {
+	var file bytes.Buffer
+	h := NewHistory(6, 3, &file)
+	for turn := 1; turn <= 200; turn++ {
+		if err := h.Record(historyExport(turn)); err != nil {
+			t.Fatal(err)
+		}
+		if len(h.stringIDs) > 20 || len(h.strings) > 20 {
+			t.Fatalf("%d strings interned after %d turns", len(h.stringIDs), turn)
+		}
+	}
+	first, last := h.Turns()
+	for turn := first; turn <= last; turn++ {
+		e, _ := h.At(turn)
+		expected := fmt.Sprintf("organism %d", turn)
+		if e.organisms[Position{X: 2, Y: 2}] != expected || e.organisms[Position{X: 1, Y: 1}] != "old" {
+			t.Fatalf("turn %d replays organisms %v", turn, e.organisms)
+		}
+	}
+
+	loaded, err := LoadHistory(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for turn := 1; turn <= 200; turn++ {
+		e, _ := loaded.At(turn)
+		expected := fmt.Sprintf("organism %d", turn)
+		if e.organisms[Position{X: 2, Y: 2}] != expected || e.genomes[Position{X: 1, Y: 1}] != "genome old" {
+			t.Fatalf("turn %d loads organisms %v and genomes %v", turn, e.organisms, e.genomes)
+		}
+	}
+}
+
+func TestLoadHistoryRequiresTheStringIDs(t *testing.T) {
+	var file bytes.Buffer
+	frame := historyFrame{Turn: 1, Keyframe: true, Strings: []string{"organism", "genome"}}
+	if err := gob.NewEncoder(&file).Encode(frame); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHistory(&file); err == nil {
+		t.Fatal("a frame without string ids was loaded")
+	}
+}
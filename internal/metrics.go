@@ -0,0 +1,253 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type DeathCause uint8
+
+const (
+	DeathCauseEnergy DeathCause = iota
+	DeathCauseAge
+	DeathCauseWater
+	DeathCauseFragmentation
+	MaxDeathCause
+)
+
+func (c DeathCause) String() string {
+	switch c {
+	case DeathCauseEnergy:
+		return "energy"
+	case DeathCauseAge:
+		return "age"
+	case DeathCauseWater:
+		return "water"
+	case DeathCauseFragmentation:
+		return "fragmentation"
+	}
+	panic(c)
+}
+
+type TurnMetrics struct {
+	Turn      int
+	Cells     [MaxCellType]int
+	Organisms int
+	Genomes   int
+	Species   int
+
+	GenomeShannon      float64
+	GenomeSimpson      float64
+	SpeciesShannon     float64
+	SpeciesSimpson     float64
+	MeanGenomeDistance float64
+	GenomeAgeMean      float64
+	GenomeAgeMedian    int
+	GenomeAgeP90       int
+	GenomeAgeMax       int
+	// TotalActivity, MeanActivity and NewActivity are the Bedau-Packard statistics of the living genomes
+	TotalActivity int64
+	MeanActivity  float64
+	NewActivity   int
+
+	CellInventory [MaxItemType]int64
+	SoilInventory [MaxItemType]int64
+	Transported   [MaxItemType]int64
+	// Shared flowed through sharing connectors between organisms, Pulled was taken by vampire connectors
+	Shared [MaxItemType]int64
+	Pulled [MaxItemType]int64
+
+	Births          int
+	Deaths          [MaxDeathCause]int
+	SeedsLaunched   int
+	SeedsGerminated int
+	ConflictsLost   int
+
+	ThinkingTime   time.Duration
+	TypeActionTime time.Duration
+	SpreadTime     time.Duration
+}
+
+func (m *TurnMetrics) TotalCells() int {
+	total := 0
+	for i := range m.Cells {
+		total += m.Cells[i]
+	}
+	return total
+}
+
+// columns flattens the metrics in a stable order shared by all the writers
+func (m *TurnMetrics) columns() ([]string, []float64) {
+	names := []string{"turn"}
+	values := []float64{float64(m.Turn)}
+	for ct := CellType(0); ct < MaxCellType; ct++ {
+		names = append(names, "cells_"+ct.String())
+		values = append(values, float64(m.Cells[ct]))
+	}
+	names = append(names, "organisms", "genomes", "species")
+	values = append(values, float64(m.Organisms), float64(m.Genomes), float64(m.Species))
+	names = append(
+		names, "genome_shannon", "genome_simpson", "species_shannon", "species_simpson", "mean_genome_distance",
+		"genome_age_mean", "genome_age_median", "genome_age_p90", "genome_age_max", "total_activity",
+		"mean_activity", "new_activity",
+	)
+	values = append(
+		values, m.GenomeShannon, m.GenomeSimpson, m.SpeciesShannon, m.SpeciesSimpson, m.MeanGenomeDistance,
+		m.GenomeAgeMean, float64(m.GenomeAgeMedian), float64(m.GenomeAgeP90), float64(m.GenomeAgeMax),
+		float64(m.TotalActivity), m.MeanActivity, float64(m.NewActivity),
+	)
+	for it := ItemType(0); it < MaxItemType; it++ {
+		names = append(
+			names, "cell_"+it.String(), "soil_"+it.String(), "transported_"+it.String(), "shared_"+it.String(),
+			"pulled_"+it.String(),
+		)
+		values = append(
+			values, float64(m.CellInventory[it]), float64(m.SoilInventory[it]), float64(m.Transported[it]),
+			float64(m.Shared[it]), float64(m.Pulled[it]),
+		)
+	}
+	names = append(names, "births")
+	values = append(values, float64(m.Births))
+	for c := DeathCause(0); c < MaxDeathCause; c++ {
+		names = append(names, "deaths_"+c.String())
+		values = append(values, float64(m.Deaths[c]))
+	}
+	names = append(
+		names, "seeds_launched", "seeds_germinated", "conflicts_lost", "thinking_seconds", "type_action_seconds",
+		"spread_seconds",
+	)
+	values = append(
+		values, float64(m.SeedsLaunched), float64(m.SeedsGerminated), float64(m.ConflictsLost),
+		m.ThinkingTime.Seconds(), m.TypeActionTime.Seconds(), m.SpreadTime.Seconds(),
+	)
+	return names, values
+}
+
+type MetricsSink interface {
+	Write(m TurnMetrics) error
+	Close() error
+}
+
+// MetricsCSVWriter writes a header and a row per turn
+type MetricsCSVWriter struct {
+	writer        *csv.Writer
+	closer        io.Closer
+	headerWritten bool
+	mx            sync.Mutex
+}
+
+func NewMetricsCSVWriter(w io.WriteCloser) *MetricsCSVWriter {
+	return &MetricsCSVWriter{writer: csv.NewWriter(w), closer: w}
+}
+
+func (w *MetricsCSVWriter) Write(m TurnMetrics) error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	names, values := m.columns()
+	if !w.headerWritten {
+		if err := w.writer.Write(names); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	row := make([]string, len(values))
+	for i := range values {
+		row[i] = strconv.FormatFloat(values[i], 'f', -1, 64)
+	}
+	if err := w.writer.Write(row); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *MetricsCSVWriter) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	return w.closer.Close()
+}
+
+// MetricsColumnsWriter keeps every column in memory and writes them as a JSON object of arrays on Close
+type MetricsColumnsWriter struct {
+	names   []string
+	columns [][]float64
+	writer  io.WriteCloser
+	mx      sync.Mutex
+}
+
+func NewMetricsColumnsWriter(w io.WriteCloser) *MetricsColumnsWriter {
+	return &MetricsColumnsWriter{writer: w}
+}
+
+func (w *MetricsColumnsWriter) Write(m TurnMetrics) error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	names, values := m.columns()
+	if w.names == nil {
+		w.names = names
+		w.columns = make([][]float64, len(names))
+	}
+	for i := range values {
+		w.columns[i] = append(w.columns[i], values[i])
+	}
+	return nil
+}
+
+func (w *MetricsColumnsWriter) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	result := make(map[string][]float64)
+	for i := range w.names {
+		result[w.names[i]] = w.columns[i]
+	}
+	if err := json.NewEncoder(w.writer).Encode(result); err != nil {
+		return err
+	}
+	return w.writer.Close()
+}
+
+func (w *World) countGerminatedSeed() {
+	w.metricsMx.Lock()
+	w.metrics.SeedsGerminated += 1
+	w.metricsMx.Unlock()
+}
+
+// Metrics returns the counters of the current turn together with the world totals
+func (w *World) Metrics() TurnMetrics {
+	w.metricsMx.Lock()
+	result := w.metrics
+	w.metricsMx.Unlock()
+	organisms := make(map[string]bool)
+	genomes := make(map[string]int)
+	w.cells.forEach(func(cell *Cell, _ Position) {
+		result.Cells[cell.cellType] += 1
+		organisms[cell.organismID] = true
+		genomes[cell.genomeID] += 1
+		for it := ItemType(0); it < MaxItemType; it++ {
+			result.CellInventory[it] += int64(cell.GetFromInventory(it))
+		}
+	})
+	result.Organisms = len(organisms)
+	result.Genomes = len(genomes)
+	genomeSpecies := w.species.update(w)
+	species := make(map[int]bool)
+	for _, id := range genomeSpecies {
+		species[id] = true
+	}
+	result.Species = len(species)
+	w.diversityMetrics(&result, genomes, genomeSpecies)
+	for i := range w.soil {
+		for it := ItemType(0); it < MaxItemType; it++ {
+			result.SoilInventory[it] += int64(w.soil[i][it])
+		}
+	}
+	return result
+}
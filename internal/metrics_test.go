@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+// closingBuffer records whether a sink closed its output
+type closingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closingBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestMetricsCSVWriterWritesAHeaderAndARowPerTurn(t *testing.T) {
+	var out closingBuffer
+	sink := NewMetricsCSVWriter(&out)
+	for turn := 1; turn <= 3; turn++ {
+		if err := sink.Write(TurnMetrics{Turn: turn, Births: turn * 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !out.closed {
+		t.Fatal("the output was not closed")
+	}
+	rows, err := csv.NewReader(&out.Buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ := (&TurnMetrics{}).columns()
+	if len(rows) != 4 || len(rows[0]) != len(names) || rows[0][0] != "turn" {
+		t.Fatalf("expected a header of %d columns and 3 rows, got %v", len(names), rows)
+	}
+	births := 0
+	for i := range names {
+		if names[i] == "births" {
+			births = i
+		}
+	}
+	for i, row := range rows[1:] {
+		if row[0] != strconv.Itoa(i+1) || row[births] != strconv.Itoa((i+1)*10) {
+			t.Fatalf("row %d is out of order: turn %s, births %s", i, row[0], row[births])
+		}
+	}
+}
+
+func TestMetricsColumnsWriterWritesTheColumnsOnClose(t *testing.T) {
+	var out closingBuffer
+	sink := NewMetricsColumnsWriter(&out)
+	for turn := 1; turn <= 3; turn++ {
+		metrics := TurnMetrics{Turn: turn}
+		metrics.Deaths[DeathCauseAge] = turn * 2
+		if err := sink.Write(metrics); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out.Len() != 0 {
+		t.Fatal("the columns were written before Close")
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !out.closed {
+		t.Fatal("the output was not closed")
+	}
+	var columns map[string][]float64
+	if err := json.Unmarshal(out.Bytes(), &columns); err != nil {
+		t.Fatal(err)
+	}
+	names, _ := (&TurnMetrics{}).columns()
+	if len(columns) != len(names) {
+		t.Fatalf("%d columns instead of %d", len(columns), len(names))
+	}
+	turns, deaths := columns["turn"], columns["deaths_age"]
+	if len(turns) != 3 || len(deaths) != 3 {
+		t.Fatalf("expected 3 values per column, got %v and %v", turns, deaths)
+	}
+	for i := range turns {
+		if turns[i] != float64(i+1) || deaths[i] != float64(2*(i+1)) {
+			t.Fatalf("turn %v with %v age deaths is out of order", turns[i], deaths[i])
+		}
+	}
+}
+
+func TestMetricsCountTheBirthsDeathsAndSeedsOfTheTurn(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	genome := &Genome{id: "g", genome: make([]uint8, GenomeSize)}
+	w.AddGenome(genome)
+	alive := Inventory{ItemTypeEnergy: 50, ItemTypeWater: 50}
+	parent := w.AddCell(Position{X: 5, Y: 5}, NewCell("g", CellTypeLeaf, alive, "p"))
+	w.AddCell(Position{X: 20, Y: 5}, NewCell("g", CellTypeLeaf, Inventory{ItemTypeWater: 50}, "e"))
+	w.AddCell(Position{X: 25, Y: 5}, NewCell("g", CellTypeLeaf, Inventory{ItemTypeEnergy: 50}, "w"))
+	old := w.AddCell(Position{X: 30, Y: 5}, NewCell("g", CellTypeLeaf, alive, "a"))
+	old.age = w.Config().MaxAge
+
+	w.CleanupTurn()
+	w.RegisterNewCell(parent, NewCell("g", CellTypeLeaf, alive, "p"), Position{X: 5, Y: 6}, genome)
+	w.RegisterNewCell(parent, NewCell("g", CellTypeSeed, alive, "p"), Position{X: 6, Y: 5}, genome)
+	w.CreateNewCells()
+	w.RemoveCells()
+	metrics := w.Metrics()
+	if metrics.Births != 2 || metrics.SeedsLaunched != 1 {
+		t.Fatalf("%d births and %d seeds instead of 2 and 1", metrics.Births, metrics.SeedsLaunched)
+	}
+	expected := [MaxDeathCause]int{DeathCauseEnergy: 1, DeathCauseAge: 1, DeathCauseWater: 1}
+	if metrics.Deaths != expected {
+		t.Fatalf("deaths by cause %v instead of %v", metrics.Deaths, expected)
+	}
+
+	w.CleanupTurn()
+	metrics = w.Metrics()
+	if metrics.Births != 0 || metrics.SeedsLaunched != 0 || metrics.Deaths != [MaxDeathCause]int{} {
+		t.Fatalf("the counters of the previous turn were kept: %+v", metrics)
+	}
+}
@@ -0,0 +1,42 @@
+package internal
+
+// diffuseMorphogens adds the emission of every cell and spreads the signals between the neighbouring cells
+// of the same organism, one hop per transport pass, before a part of them decays
+func (w *World) diffuseMorphogens() {
+	cells := w.sortedCells()
+	index := make(map[*Cell]int, len(cells))
+	for i, cell := range cells {
+		index[cell] = i
+	}
+	var edges [][2]int
+	for i, cell := range cells {
+		ns := w.Neighbours(w.GetPosition(cell))
+		for k := range ns {
+			neighbour := w.GetCellByPosition(ns[k])
+			if neighbour != nil && neighbour.organismID == cell.organismID {
+				edges = append(edges, [2]int{i, index[neighbour]})
+			}
+		}
+	}
+	kept := 100 - max(0, min(100, w.config.MorphogenDecay))
+	levels := make([]int, len(cells))
+	gradient := make([]int, len(cells))
+	for channel := 0; channel < MorphogenCount; channel++ {
+		for i, cell := range cells {
+			levels[i] = min(MaxMorphogen, int(cell.morphogens[channel])+int(cell.emission[channel]))
+		}
+		for pass := 0; pass < w.config.TransportPasses && len(edges) > 0; pass++ {
+			copy(gradient, levels)
+			for _, edge := range edges {
+				from, to := edge[0], edge[1]
+				if amount := (gradient[from] - gradient[to]) / transportDivider; amount > 0 {
+					levels[from] -= amount
+					levels[to] += amount
+				}
+			}
+		}
+		for i, cell := range cells {
+			cell.morphogens[channel] = int16(levels[i] * kept / 100)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package internal
+
+import "testing"
+
+func TestEmittedMorphogensSpreadWithinTheOrganism(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	config := w.Config()
+	config.MorphogenDecay = 10
+	if err := w.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	emitter := genomeCell(w, Position{X: 5, Y: 5}, "g", GeneEmitMorphogen, 1, 200, GeneMove)
+	near := w.AddCell(Position{X: 6, Y: 5}, NewCell("g", CellTypeLeaf, Inventory{}, "o"))
+	far := w.AddCell(Position{X: 7, Y: 5}, NewCell("g", CellTypeLeaf, Inventory{}, "o"))
+	stranger := w.AddCell(Position{X: 5, Y: 6}, NewCell("g", CellTypeLeaf, Inventory{}, "p"))
+
+	w.CleanupTurn()
+	w.ExecuteCellGenomes()
+	if emitter.emission[1] != 200 {
+		t.Fatalf("the gene set the emission to %d instead of 200", emitter.emission[1])
+	}
+	w.diffuseMorphogens()
+	total := emitter.morphogens[1] + near.morphogens[1] + far.morphogens[1]
+	if emitter.morphogens[1] <= near.morphogens[1] || near.morphogens[1] <= 0 || total > 180 {
+		t.Fatalf(
+			"the signal did not spread down the organism and decay: %d, %d, %d",
+			emitter.morphogens[1], near.morphogens[1], far.morphogens[1],
+		)
+	}
+	if stranger.morphogens[1] != 0 || emitter.morphogens[0] != 0 {
+		t.Fatalf("the signal reached another organism or channel: %d, %d", stranger.morphogens[1], emitter.morphogens[0])
+	}
+	// the emission keeps adding to the signal every turn
+	w.diffuseMorphogens()
+	if emitter.morphogens[1]+near.morphogens[1]+far.morphogens[1] <= total {
+		t.Fatal("the signal did not grow with a second emission")
+	}
+}
+
+func TestMorphogenGradientConditionLooksUpTheOrganism(t *testing.T) {
+	for _, c := range []struct {
+		organism string
+		level    int16
+		position uint8
+	}{{"o", 50, 4}, {"o", 0, 6}, {"p", 50, 6}} {
+		w := withBudget(t, InstructionBudget)
+		cell := genomeCell(
+			w, Position{X: 5, Y: 5}, "g",
+			GeneIf, GeneCommand(CompareMorphogenGradient), 2, GeneMove, 0, GeneMove,
+		)
+		cell.direction = DirectionNorth
+		cell.morphogens[2] = 10
+		neighbour := w.AddCell(Position{X: 5, Y: 4}, NewCell("g", CellTypeLeaf, Inventory{}, c.organism))
+		neighbour.morphogens[2] = c.level
+
+		w.CleanupTurn()
+		w.ExecuteCellGenomes()
+		if cell.genomePosition != c.position {
+			t.Fatalf(
+				"a neighbour of %s with the level %d continued at %d instead of %d",
+				c.organism, c.level, cell.genomePosition, c.position,
+			)
+		}
+	}
+}
@@ -1,108 +1,258 @@
 package internal
 
 import (
-	"sync"
+	"sort"
+
+	"github.com/google/uuid"
 )
 
+// Organism lives from the birth of its first cell until its last cell dies
 type Organism struct {
-	cells            []*Cell
-	transferredCells []*Cell
+	id        string
+	genomeID  string
+	birthTurn int
+	deathTurn int
+	cells     map[*Cell]bool
+	cellsBorn int
+	cellsDied int
+	peakSize  int
+}
+
+func newOrganism(id, genomeID string, turn int) *Organism {
+	return &Organism{id: id, genomeID: genomeID, birthTurn: turn, deathTurn: -1, cells: make(map[*Cell]bool)}
+}
+
+func (o *Organism) GetID() string {
+	return o.id
+}
+
+// GetFounderGenomeID is the genome of the first cell, later cells may carry its mutations
+func (o *Organism) GetFounderGenomeID() string {
+	return o.genomeID
+}
+
+func (o *Organism) BirthTurn() int {
+	return o.birthTurn
+}
+
+// DeathTurn is -1 while the organism is alive
+func (o *Organism) DeathTurn() int {
+	return o.deathTurn
+}
+
+func (o *Organism) Alive() bool {
+	return o.deathTurn == -1
+}
+
+func (o *Organism) Size() int {
+	return len(o.cells)
 }
 
-func (o *Organism) RegisterCell(cell *Cell) {
-	o.cells = append(o.cells, cell)
+func (o *Organism) PeakSize() int {
+	return o.peakSize
 }
 
-func (o *Organism) shouldTransferEnergy(world *World, from *Cell, to *Cell) bool {
-	if from.cellType == CellTypeConnector {
-		relation := world.GetGenome(from.genomeID).GetGene(from.genomePosition) % uint8(MaxRelationType)
-		return relationMatch(from, to, Relation(relation))
+func (o *Organism) CellsBorn() int {
+	return o.cellsBorn
+}
+
+func (o *Organism) CellsDied() int {
+	return o.cellsDied
+}
+
+func (o *Organism) Cells() []*Cell {
+	result := make([]*Cell, 0, len(o.cells))
+	for c := range o.cells {
+		result = append(result, c)
 	}
+	return result
+}
 
-	if to.cellType == CellTypeConnector {
-		relation := world.GetGenome(to.genomeID).GetGene(to.genomePosition) % uint8(MaxRelationType)
-		return relationMatch(to, from, Relation(relation))
+// Inventory sums the inventories of the living cells
+func (o *Organism) Inventory() Inventory {
+	var result Inventory
+	for c := range o.cells {
+		for it := ItemType(0); it < MaxItemType; it++ {
+			result[it] += c.GetFromInventory(it)
+		}
 	}
+	return result
+}
 
-	return relationMatch(from, to, RelationSameOrganism)
+func (o *Organism) addCell(cell *Cell) {
+	o.cells[cell] = true
+	o.cellsBorn += 1
+	o.peakSize = max(o.peakSize, len(o.cells))
 }
 
-func (o *Organism) calculateDonationTarget(w *World, cell *Cell, ns []Position, itemType ItemType) *Cell {
-	var poorestFriend *Cell
-	for i := range ns {
-		n := w.GetCellByPosition(ns[i])
-		if n != nil {
-			if !o.shouldTransferEnergy(w, cell, n) {
-				continue
-			}
+func (o *Organism) removeCell(cell *Cell) {
+	delete(o.cells, cell)
+	o.cellsDied += 1
+}
 
-			if poorestFriend == nil {
-				poorestFriend = n
-				continue
-			}
-			if poorestFriend.GetFromInventory(itemType) > n.GetFromInventory(itemType) {
-				poorestFriend = n
-			}
+// Organisms returns snapshots of the living organisms together with the ones which died since
+// ForgetDeadOrganisms was called. The cells of a snapshot are copies, since the slots of dead cells are
+// reused, so the snapshots do not change with the world
+func (w *World) Organisms() map[string]*Organism {
+	result := make(map[string]*Organism, len(w.organisms))
+	for id, organism := range w.organisms {
+		snapshot := *organism
+		snapshot.cells = make(map[*Cell]bool, len(organism.cells))
+		for cell := range organism.cells {
+			copied := *cell
+			snapshot.cells[&copied] = true
 		}
+		result[id] = &snapshot
 	}
-	if poorestFriend == nil {
-		return nil
+	return result
+}
+
+func (w *World) addOrganismCell(cell *Cell) {
+	organism, found := w.organisms[cell.organismID]
+	if !found {
+		organism = newOrganism(cell.organismID, cell.genomeID, w.turn)
+		w.organisms[cell.organismID] = organism
 	}
-	if poorestFriend.GetFromInventory(itemType) >= cell.GetFromInventory(itemType) {
+	organism.addCell(cell)
+}
+
+// removeOrganismCell returns the organism when its last cell was removed
+func (w *World) removeOrganismCell(cell *Cell) *Organism {
+	organism := w.organisms[cell.organismID]
+	organism.removeCell(cell)
+	if organism.Size() > 0 {
 		return nil
 	}
-	return poorestFriend
+	organism.deathTurn = w.turn
+	return organism
 }
 
-func (o *Organism) HandleResourcesFlow(world *World, wg *sync.WaitGroup) {
-	defer wg.Done()
-	if len(o.cells) <= 1 {
-		return
+// ForgetDeadOrganisms drops the dead organisms, it is called once the turn was exported and observed
+func (w *World) ForgetDeadOrganisms() {
+	for id, organism := range w.organisms {
+		if !organism.Alive() {
+			delete(w.organisms, id)
+		}
 	}
-	var wgC sync.WaitGroup
-	var changesMx sync.Mutex
-	wgC.Add(len(o.cells))
-	type change struct {
-		c      *Cell
-		change int16
+}
+
+// canTransfer allows the flow within the organism, sharing connectors open it to foreign organisms both ways
+func canTransfer(world *World, from *Cell, to *Cell) bool {
+	if relationMatch(from, to, RelationSameOrganism) {
+		return true
+	}
+	for _, c := range []*Cell{from, to} {
+		if c.cellType != CellTypeConnector {
+			continue
+		}
+		if mode, _ := world.connectorGenes(c); mode == ConnectorShare {
+			return true
+		}
 	}
-	changes := make(map[ItemType][]change)
-	for i := ItemType(0); i < MaxItemType; i++ {
-		changes[i] = make([]change, 0)
+	return false
+}
+
+// splitFragments gives new organisms to the parts of the changed organisms which are no longer connected,
+// the largest part keeps the organism
+func (w *World) splitFragments(changed map[string]bool) {
+	ids := make([]string, 0, len(changed))
+	for id := range changed {
+		ids = append(ids, id)
 	}
-	for ix := range o.cells {
-		cell := o.cells[ix]
-		go func() {
-			defer wgC.Done()
-			if cell.cellType == CellTypeFlower {
-				// flowers only receive resources
-				return
+	sort.Strings(ids)
+	for _, id := range ids {
+		organism, found := w.organisms[id]
+		if !found || organism.Size() < 2 {
+			continue
+		}
+		fragments := w.connectedFragments(organism)
+		if len(fragments) < 2 {
+			continue
+		}
+		for _, fragment := range fragments[1:] {
+			detached := newOrganism(uuid.NewString(), organism.genomeID, w.turn)
+			for _, cell := range fragment {
+				delete(organism.cells, cell)
+				cell.organismID = detached.id
+				detached.cells[cell] = true
 			}
-			pos := world.GetPosition(cell)
-			ns := pos.Neighbours()
-			for it := ItemType(0); it < MaxItemType; it++ {
-				if cell.GetFromInventory(it) < 2*itemSpreadStep(it) {
-					continue
+			detached.peakSize = len(fragment)
+			w.organisms[detached.id] = detached
+			w.events.Emit(OrganismSplit{
+				EventHeader: w.header(EventOrganismSplit), Organism: organism.id, Fragment: detached.id,
+				Size: len(fragment),
+			})
+		}
+		if w.config.FragmentRequiredCellType < 0 {
+			continue
+		}
+		for _, fragment := range fragments {
+			if !hasCellType(fragment, CellType(w.config.FragmentRequiredCellType)) {
+				for _, cell := range fragment {
+					w.killCell(cell, DeathCauseFragmentation)
 				}
+			}
+		}
+	}
+}
+
+func hasCellType(cells []*Cell, ct CellType) bool {
+	for i := range cells {
+		if cells[i].cellType == ct {
+			return true
+		}
+	}
+	return false
+}
 
-				target := o.calculateDonationTarget(world, cell, ns, it)
-				if target == nil {
-					continue
+// connectedFragments returns the parts of the organism connected by neighbouring cells, largest first
+func (w *World) connectedFragments(organism *Organism) [][]*Cell {
+	byPosition := make(map[Position]*Cell, len(organism.cells))
+	positions := make([]Position, 0, len(organism.cells))
+	for cell := range organism.cells {
+		byPosition[w.GetPosition(cell)] = cell
+		positions = append(positions, w.GetPosition(cell))
+	}
+	// the walk starts from sorted positions so the cells of the fragments die in the same order every run
+	sort.Slice(positions, func(i, j int) bool {
+		return positionLess(positions[i], positions[j])
+	})
+	visited := make(map[*Cell]bool, len(organism.cells))
+	var result [][]*Cell
+	for _, pos := range positions {
+		start := byPosition[pos]
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		fragment := []*Cell{start}
+		for i := 0; i < len(fragment); i++ {
+			ns := w.Neighbours(w.GetPosition(fragment[i]))
+			for k := range ns {
+				if n, found := byPosition[ns[k]]; found && !visited[n] {
+					visited[n] = true
+					fragment = append(fragment, n)
 				}
-				changesMx.Lock()
-				changes[it] = append(
-					changes[it], change{c: cell, change: -itemSpreadStep(it)},
-					change{c: target, change: itemSpreadStep(it)},
-				)
-				changesMx.Unlock()
 			}
-		}()
+		}
+		result = append(result, fragment)
 	}
-	wgC.Wait()
-
-	for it := range changes {
-		for i := range changes[it] {
-			changes[it][i].c.AddToInventory(it, changes[it][i].change)
+	// the order only depends on the cells so the same part keeps the organism every time
+	lowest := func(cells []*Cell) Position {
+		result := w.GetPosition(cells[0])
+		for _, cell := range cells[1:] {
+			pos := w.GetPosition(cell)
+			if positionLess(pos, result) {
+				result = pos
+			}
 		}
+		return result
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i]) != len(result[j]) {
+			return len(result[i]) > len(result[j])
+		}
+		return positionLess(lowest(result[i]), lowest(result[j]))
+	})
+	return result
 }
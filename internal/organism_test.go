@@ -0,0 +1,112 @@
+package internal
+
+import "testing"
+
+func TestDeadOrganismsAreKeptUntilForgotten(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	first := w.AddCell(Position{X: 1, Y: 1}, NewCell("g", CellTypeLeaf, Inventory{}, "o"))
+	w.AddCell(Position{X: 2, Y: 1}, NewCell("g", CellTypeLeaf, Inventory{}, "o"))
+	snapshot := w.Organisms()["o"]
+
+	w.CleanupTurn()
+	w.killCell(first, DeathCauseEnergy)
+	if snapshot.Size() != 2 {
+		t.Fatalf("the snapshot changed to %d cells with the world", snapshot.Size())
+	}
+	w.killCell(w.GetCellByPosition(Position{X: 2, Y: 1}), DeathCauseEnergy)
+	w.CleanupTurn()
+	dead := w.Organisms()["o"]
+	if dead == nil || dead.Alive() || dead.DeathTurn() != 1 || dead.PeakSize() != 2 {
+		t.Fatalf("the dead organism is not kept for the observers: %+v", dead)
+	}
+	w.ForgetDeadOrganisms()
+	if len(w.Organisms()) != 0 {
+		t.Fatal("the dead organism is still kept")
+	}
+}
+
+func TestSnapshotsKeepTheirCellsWhenSlotsAreReused(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	var cells []*Cell
+	for x := int64(1); x <= 3; x++ {
+		cell := NewCell("g", CellTypeLeaf, Inventory{ItemTypeEnergy: 7}, "o")
+		cells = append(cells, w.AddCell(Position{X: x, Y: 1}, cell))
+	}
+	snapshot := w.Organisms()["o"]
+
+	w.CleanupTurn()
+	for _, cell := range cells {
+		w.killCell(cell, DeathCauseEnergy)
+	}
+	for x := int64(1); x <= 3; x++ {
+		reborn := w.AddCell(Position{X: x, Y: 5}, NewCell("h", CellTypeRoot, Inventory{ItemTypeEnergy: 50}, "p"))
+		reborn.AddToInventory(ItemTypeWater, 9)
+	}
+
+	if snapshot.Size() != 3 || snapshot.Inventory() != (Inventory{ItemTypeEnergy: 21}) {
+		t.Fatalf("the snapshot changed to %d cells holding %v", snapshot.Size(), snapshot.Inventory())
+	}
+	for _, cell := range snapshot.Cells() {
+		if cell.genomeID != "g" || cell.cellType != CellTypeLeaf || cell.organismID != "o" {
+			t.Fatalf("the snapshot shows the reused cell %+v", cell)
+		}
+	}
+}
+
+// splitOrganism cuts a line of three leaves and two roots apart by killing the trunk between them
+func splitOrganism(t *testing.T, requiredCellType int) (*World, []OrganismSplit) {
+	w := NewSeededWorld(WorldSize, 1)
+	config := w.Config()
+	config.FragmentRequiredCellType = requiredCellType
+	if err := w.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	var splits []OrganismSplit
+	w.Events().Subscribe(func(e Event) {
+		if split, ok := e.(OrganismSplit); ok {
+			splits = append(splits, split)
+		}
+	})
+	cellTypes := []CellType{CellTypeLeaf, CellTypeLeaf, CellTypeLeaf, CellTypeTrunk, CellTypeRoot, CellTypeRoot}
+	for x := range cellTypes {
+		w.AddCell(Position{X: int64(x + 1), Y: 1}, NewCell("g", cellTypes[x], Inventory{ItemTypeEnergy: 9}, "o"))
+	}
+
+	w.CleanupTurn()
+	w.killCell(w.GetCellByPosition(Position{X: 4, Y: 1}), DeathCauseEnergy)
+	w.splitFragments(w.changedOrganisms)
+	return w, splits
+}
+
+func TestSplitKeepsTheIdOnTheLargestFragment(t *testing.T) {
+	w, splits := splitOrganism(t, -1)
+	if len(splits) != 1 || splits[0].Organism != "o" || splits[0].Size != 2 || splits[0].Fragment == "o" {
+		t.Fatalf("expected a single split of a fragment of 2 cells from o, got %+v", splits)
+	}
+	if organism := w.Organisms()["o"]; organism.Size() != 3 {
+		t.Fatalf("the organism kept %d cells instead of the 3 leaves", organism.Size())
+	}
+	for x := int64(5); x <= 6; x++ {
+		if cell := w.GetCellByPosition(Position{X: x, Y: 1}); cell.organismID != splits[0].Fragment {
+			t.Fatalf("the root at %d belongs to %s instead of the fragment", x, cell.organismID)
+		}
+	}
+	if fragment := w.Organisms()[splits[0].Fragment]; fragment.Size() != 2 || fragment.PeakSize() != 2 {
+		t.Fatalf("the fragment organism has %d cells and a peak of %d", fragment.Size(), fragment.PeakSize())
+	}
+}
+
+func TestSplitKillsTheFragmentsWithoutTheRequiredCellType(t *testing.T) {
+	w, splits := splitOrganism(t, int(CellTypeLeaf))
+	if len(splits) != 1 {
+		t.Fatalf("expected a single split, got %+v", splits)
+	}
+	if w.metrics.Deaths[DeathCauseFragmentation] != 2 {
+		t.Fatalf("%d cells died of fragmentation instead of the 2 roots", w.metrics.Deaths[DeathCauseFragmentation])
+	}
+	for x := int64(1); x <= 6; x++ {
+		if cell := w.GetCellByPosition(Position{X: x, Y: 1}); (cell != nil) != (x <= 3) {
+			t.Fatalf("the cell at %d is %v, only the leaves should be alive", x, cell)
+		}
+	}
+}
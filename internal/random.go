@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Random is a goroutine safe random source, every world owns one so seeded worlds do not share state
+type Random struct {
+	rand *rand.Rand
+	mx   sync.Mutex
+}
+
+var defaultRandom = NewRandom(time.Now().UnixNano())
+
+func NewRandom(seed int64) *Random {
+	return &Random{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (r *Random) Float32() float32 {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.rand.Float32()
+}
+
+func (r *Random) Uint32() uint32 {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.rand.Uint32()
+}
+
+func (r *Random) Int63() int64 {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.rand.Int63()
+}
+
+// Split derives an independent source, splitting in a fixed order keeps the results reproducible
+func (r *Random) Split() *Random {
+	source := splitMix(r.Int63())
+	return &Random{rand: rand.New(&source)}
+}
+
+// splitMix is a small random source, cheap enough to create one per cell and phase
+type splitMix uint64
+
+func (s *splitMix) Seed(seed int64) {
+	*s = splitMix(seed)
+}
+
+func (s *splitMix) Uint64() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := uint64(*s)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *splitMix) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
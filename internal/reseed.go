@@ -0,0 +1,291 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// DefaultSeedChance is the share of free tiles receiving a seed when the whole world is seeded
+const DefaultSeedChance = 0.03
+
+// GenomeSource provides the genomes of the planted seeds
+type GenomeSource interface {
+	// NextGenome may return nil when the source has nothing to offer
+	NextGenome(w *World) *Genome
+}
+
+type RandomGenomes struct{}
+
+func (RandomGenomes) NextGenome(w *World) *Genome {
+	return NewRandomGenome(w.random)
+}
+
+// GenomeLibrary picks genomes saved from earlier runs
+type GenomeLibrary struct {
+	genomes []*Genome
+}
+
+func NewGenomeLibrary(genomes []*Genome) *GenomeLibrary {
+	return &GenomeLibrary{genomes: genomes}
+}
+
+func (l *GenomeLibrary) NextGenome(w *World) *Genome {
+	if len(l.genomes) == 0 {
+		return nil
+	}
+	return l.genomes[w.random.Uint32()%uint32(len(l.genomes))]
+}
+
+// LoadGenomeLibrary reads genomes as hex encoded genes, one per line, skipping empty lines and # comments
+func LoadGenomeLibrary(r io.Reader) ([]*Genome, error) {
+	var result []*Genome
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		genes, err := hex.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("genome library line %d: %w", line, err)
+		}
+		genome, err := NewGenomeFromGenes(genes)
+		if err != nil {
+			return nil, fmt.Errorf("genome library line %d: %w", line, err)
+		}
+		result = append(result, genome)
+	}
+	return result, scanner.Err()
+}
+
+func WriteGenomeLibrary(w io.Writer, genomes []*Genome) error {
+	for i := range genomes {
+		if _, err := fmt.Fprintf(w, "# %s\n%s\n", genomes[i].id, hex.EncodeToString(genomes[i].genome)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TopGenomes picks among the genomes which gave birth to the most cells over the whole run
+type TopGenomes struct {
+	world  *World
+	n      int
+	births map[string]int
+	// pruneAt is the amount of counted genomes at which the extinct ones are forgotten
+	pruneAt   int
+	top       []string
+	topTurn   int
+	mx        sync.Mutex
+	genomesMx sync.Mutex
+}
+
+func NewTopGenomes(w *World, n int) *TopGenomes {
+	t := &TopGenomes{world: w, n: n, births: make(map[string]int), pruneAt: 2 * max(1, n), topTurn: -1}
+	w.events.Subscribe(func(e Event) {
+		if born, ok := e.(CellBorn); ok {
+			t.mx.Lock()
+			t.births[born.Genome] += 1
+			if len(t.births) >= t.pruneAt {
+				t.prune()
+			}
+			t.mx.Unlock()
+		}
+	})
+	return t
+}
+
+// prune forgets the extinct genomes outside of the top, their births do not grow anymore so they can not
+// get back into it. Pruning only once the counted genomes doubled spreads the cost over the births between
+func (t *TopGenomes) prune() {
+	living := make(map[string]bool)
+	t.world.cells.forEach(func(c *Cell, _ Position) {
+		living[c.genomeID] = true
+	})
+	ranked := t.ranked()
+	for _, id := range ranked[min(t.n, len(ranked)):] {
+		if !living[id] {
+			delete(t.births, id)
+		}
+	}
+	t.pruneAt = 2 * max(t.n, len(t.births))
+}
+
+// Top returns the ids of the best genomes, most births first
+func (t *TopGenomes) Top() []string {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	result := t.ranked()
+	return result[:min(t.n, len(result))]
+}
+
+// ranked returns the ids of every counted genome, most births first
+func (t *TopGenomes) ranked() []string {
+	result := make([]string, 0, len(t.births))
+	for id := range t.births {
+		result = append(result, id)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if t.births[result[i]] != t.births[result[j]] {
+			return t.births[result[i]] > t.births[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+func (t *TopGenomes) NextGenome(w *World) *Genome {
+	t.genomesMx.Lock()
+	if t.topTurn != w.turn {
+		t.top = t.Top()
+		t.topTurn = w.turn
+	}
+	top := t.top
+	t.genomesMx.Unlock()
+	if len(top) == 0 {
+		return nil
+	}
+	return w.GetGenome(top[w.random.Uint32()%uint32(len(top))])
+}
+
+// PlantSeed places a fully charged seed of a new organism on the ground
+func (w *World) PlantSeed(pos Position, genome *Genome) {
+	w.AddGenome(genome)
+	seed := w.AddCell(
+		pos,
+		NewCell(
+			genome.id, CellTypeSeed, Inventory{ItemTypeWater: WaterMaxAmount, ItemTypeEnergy: MaxEnergy},
+			uuid.NewString(),
+		),
+	)
+	w.emitSeedLanded(seed)
+}
+
+func (w *World) occupiedPositions() map[Position]bool {
+	result := make(map[Position]bool, w.cells.count)
+	w.cells.forEach(func(_ *Cell, pos Position) {
+		result[pos] = true
+	})
+	return result
+}
+
+// SeedWorld plants seeds on the free tiles with the given chance and returns the amount of planted seeds
+func (w *World) SeedWorld(chance float32, source GenomeSource) int {
+	occupied := w.occupiedPositions()
+	planted := 0
+	for i := int64(0); i < w.size; i++ {
+		for j := int64(0); j < w.size; j++ {
+			pos := w.NewPosition(i, j)
+			if occupied[pos] || w.random.Float32() > chance {
+				continue
+			}
+			genome := source.NextGenome(w)
+			if genome == nil {
+				return planted
+			}
+			w.PlantSeed(pos, genome)
+			planted += 1
+		}
+	}
+	return planted
+}
+
+// ReseedPolicy decides whether new seeds are planted, it is called once every turn is complete
+type ReseedPolicy interface {
+	AfterTurn(w *World)
+}
+
+type NeverReseed struct{}
+
+func (NeverReseed) AfterTurn(*World) {}
+
+// ExtinctionReseed seeds the whole world once there was no sprout or flower for the given amount of turns
+type ExtinctionReseed struct {
+	turns   int
+	source  GenomeSource
+	counter int
+}
+
+func NewExtinctionReseed(turns int, source GenomeSource) *ExtinctionReseed {
+	return &ExtinctionReseed{turns: turns, source: source}
+}
+
+func (p *ExtinctionReseed) AfterTurn(w *World) {
+	growing := false
+	w.cells.forEach(func(c *Cell, _ Position) {
+		growing = growing || c.cellType == CellTypeSprout || c.cellType == CellTypeFlower
+	})
+	if growing {
+		p.counter = 0
+		return
+	}
+	p.counter += 1
+	if p.counter >= p.turns {
+		w.SeedWorld(DefaultSeedChance, p.source)
+		p.counter = 0
+	}
+}
+
+// ImmigrationReseed plants a few seeds on random free tiles periodically
+type ImmigrationReseed struct {
+	interval int
+	amount   int
+	source   GenomeSource
+}
+
+func NewImmigrationReseed(interval, amount int, source GenomeSource) *ImmigrationReseed {
+	return &ImmigrationReseed{interval: max(1, interval), amount: amount, source: source}
+}
+
+func (p *ImmigrationReseed) AfterTurn(w *World) {
+	if w.turn%p.interval != 0 {
+		return
+	}
+	occupied := w.occupiedPositions()
+	planted := 0
+	// random tiles are tried a limited amount of times, a crowded world receives fewer immigrants
+	for attempt := 0; attempt < p.amount*10 && planted < p.amount; attempt++ {
+		pos := w.NewPosition(int64(w.random.Uint32()%uint32(w.size)), int64(w.random.Uint32()%uint32(w.size)))
+		if occupied[pos] {
+			continue
+		}
+		genome := p.source.NextGenome(w)
+		if genome == nil {
+			return
+		}
+		w.PlantSeed(pos, genome)
+		occupied[pos] = true
+		planted += 1
+	}
+}
+
+// LiveGenomes returns the genomes of the living cells, the ones with the most cells first
+func (w *World) LiveGenomes() []*Genome {
+	cells := make(map[string]int)
+	w.cells.forEach(func(c *Cell, _ Position) {
+		cells[c.genomeID] += 1
+	})
+	ids := make([]string, 0, len(cells))
+	for id := range cells {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if cells[ids[i]] != cells[ids[j]] {
+			return cells[ids[i]] > cells[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	result := make([]*Genome, len(ids))
+	for i := range ids {
+		result[i] = w.GetGenome(ids[i])
+	}
+	return result
+}
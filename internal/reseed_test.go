@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestExtinctionReseedWaitsForTurnsWithoutGrowth(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	sprout := w.AddCell(Position{X: 1, Y: 1}, NewCell("g", CellTypeSprout, Inventory{}, "o"))
+	policy := NewExtinctionReseed(3, RandomGenomes{})
+	for i := 0; i < 5; i++ {
+		policy.AfterTurn(w)
+	}
+	if w.CellCount() != 1 {
+		t.Fatal("the world was reseeded while a sprout was growing")
+	}
+
+	sprout.cellType = CellTypeLeaf
+	policy.AfterTurn(w)
+	policy.AfterTurn(w)
+	if w.CellCount() != 1 {
+		t.Fatal("the world was reseeded before the turns without growth passed")
+	}
+	policy.AfterTurn(w)
+	if w.CellCount() == 1 {
+		t.Fatal("the world was not reseeded after 3 turns without growth")
+	}
+}
+
+func TestImmigrationReseedPlantsTheLibraryGenomesPeriodically(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	genome := &Genome{id: "immigrant", genome: make([]uint8, GenomeSize)}
+	policy := NewImmigrationReseed(5, 4, NewGenomeLibrary([]*Genome{genome}))
+	for turn := 1; turn <= 5; turn++ {
+		w.CleanupTurn()
+		policy.AfterTurn(w)
+		expected := 0
+		if turn == 5 {
+			expected = 4
+		}
+		if w.CellCount() != expected {
+			t.Fatalf("%d seeds after turn %d instead of %d", w.CellCount(), turn, expected)
+		}
+	}
+	w.cells.forEach(func(c *Cell, _ Position) {
+		if c.cellType != CellTypeSeed || c.genomeID != "immigrant" {
+			t.Fatalf("planted a %v of genome %s instead of an immigrant seed", c.cellType, c.genomeID)
+		}
+	})
+}
+
+func TestTopGenomesOrdersByBirths(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	top := NewTopGenomes(w, 2)
+	for _, genome := range []string{"c", "b", "a", "b", "a", "b", "a"} {
+		w.events.Emit(CellBorn{EventHeader: w.header(EventCellBorn), Genome: genome})
+	}
+	if result := top.Top(); !reflect.DeepEqual(result, []string{"a", "b"}) {
+		t.Fatalf("top genomes %v instead of the most born a and b", result)
+	}
+}
+
+func TestGenomeLibraryRoundTrip(t *testing.T) {
+	genes := make([]uint8, GenomeSize)
+	for i := range genes {
+		genes[i] = uint8(i)
+	}
+	var library bytes.Buffer
+	if err := WriteGenomeLibrary(&library, []*Genome{{id: "g", genome: genes}}); err != nil {
+		t.Fatal(err)
+	}
+	genomes, err := LoadGenomeLibrary(&library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(genomes) != 1 || !bytes.Equal(genomes[0].genome, genes) {
+		t.Fatalf("loaded %d genomes instead of the written one", len(genomes))
+	}
+}
+
+func TestTopGenomesForgetsExtinctGenomesOutsideTheTop(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	top := NewTopGenomes(w, 2)
+	w.AddCell(Position{X: 1, Y: 1}, NewCell("living", CellTypeLeaf, Inventory{}, "o"))
+	born := func(genome string, births int) {
+		for i := 0; i < births; i++ {
+			w.events.Emit(CellBorn{EventHeader: w.header(EventCellBorn), Genome: genome})
+		}
+	}
+	born("living", 1)
+	born("best", 5)
+	born("second", 4)
+	for i := 0; i < 1000; i++ {
+		born(fmt.Sprintf("extinct %d", i), 2)
+	}
+	if len(top.births) > 10 {
+		t.Fatalf("%d genomes are counted for the top 2", len(top.births))
+	}
+	if top.births["living"] != 1 {
+		t.Fatal("a living genome was forgotten")
+	}
+	if result := top.Top(); !reflect.DeepEqual(result, []string{"best", "second"}) {
+		t.Fatalf("top genomes %v instead of best and second", result)
+	}
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SchedulerType decides how the cells of a parallel phase are spread over goroutines
+type SchedulerType uint8
+
+const (
+	// SchedulerChunks runs square chunks of the grid on a persistent pool of workers
+	SchedulerChunks SchedulerType = iota
+	// SchedulerPerCell starts a goroutine for every cell
+	SchedulerPerCell
+	MaxSchedulerType
+)
+
+// ChunkSize is the side of the square of tiles a worker takes at once
+const ChunkSize = 16
+
+func (s SchedulerType) String() string {
+	switch s {
+	case SchedulerChunks:
+		return "chunks"
+	case SchedulerPerCell:
+		return "cell"
+	}
+	panic(s)
+}
+
+func ParseSchedulerType(value string) (SchedulerType, error) {
+	for s := SchedulerType(0); s < MaxSchedulerType; s++ {
+		if s.String() == value {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown scheduler %q", value)
+}
+
+// runCells calls the function for every cell in parallel. The cells read their neighbours from the frozen
+// view and buffer their writes to other tiles, so the chunks need neither a halo nor a checkerboard order
+func (w *World) runCells(cells []*Cell, f func(c *Cell)) {
+	if w.config.Scheduler == SchedulerPerCell {
+		var wg sync.WaitGroup
+		wg.Add(len(cells))
+		for _, cell := range cells {
+			go func(c *Cell) {
+				defer wg.Done()
+				f(c)
+			}(cell)
+		}
+		wg.Wait()
+		return
+	}
+
+	chunks := w.chunkCells(cells)
+	workers := w.config.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(chunks))
+	next := atomic.Int64{}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	pool := sharedWorkerPool()
+	for i := 0; i < workers; i++ {
+		pool.jobs <- func() {
+			defer wg.Done()
+			for {
+				chunk := int(next.Add(1)) - 1
+				if chunk >= len(chunks) {
+					return
+				}
+				for _, cell := range chunks[chunk] {
+					f(cell)
+				}
+			}
+		}
+	}
+	wg.Wait()
+}
+
+// workerPool runs the jobs of the parallel phases on goroutines which live as long as the program
+type workerPool struct {
+	jobs chan func()
+}
+
+func newWorkerPool(size int) *workerPool {
+	p := &workerPool{jobs: make(chan func())}
+	for i := 0; i < size; i++ {
+		go func() {
+			for job := range p.jobs {
+				job()
+			}
+		}()
+	}
+	return p
+}
+
+// sharedWorkerPool is started by the first parallel phase and shared by all the worlds, more workers than
+// GOMAXPROCS only queue their jobs
+var sharedWorkerPool = sync.OnceValue(func() *workerPool {
+	return newWorkerPool(runtime.GOMAXPROCS(0))
+})
+
+// chunkCells groups the cells by their chunk, the empty chunks are left out
+func (w *World) chunkCells(cells []*Cell) [][]*Cell {
+	chunksPerRow := (w.size + ChunkSize - 1) / ChunkSize
+	index := make(map[int64]int)
+	var chunks [][]*Cell
+	for _, cell := range cells {
+		pos := w.GetPosition(cell)
+		key := pos.Y/ChunkSize*chunksPerRow + pos.X/ChunkSize
+		i, found := index[key]
+		if !found {
+			i = len(chunks)
+			index[key] = i
+			chunks = append(chunks, nil)
+		}
+		chunks[i] = append(chunks[i], cell)
+	}
+	return chunks
+}
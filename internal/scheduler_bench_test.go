@@ -0,0 +1,46 @@
+package internal
+
+import "testing"
+
+func newBenchWorld(b *testing.B, scheduler SchedulerType, workers int) *World {
+	w := NewSeededWorld(WorldSize, 1)
+	config := w.Config()
+	config.Scheduler = scheduler
+	config.Workers = workers
+	if err := w.SetConfig(config); err != nil {
+		b.Fatal(err)
+	}
+	w.SeedWorld(DefaultSeedChance, RandomGenomes{})
+	return w
+}
+
+// BenchmarkStep compares the schedulers on worlds with the same seed, an extinct world is seeded again
+func BenchmarkStep(b *testing.B) {
+	for _, c := range []struct {
+		name      string
+		scheduler SchedulerType
+		workers   int
+	}{
+		{"chunks", SchedulerChunks, 0},
+		{"chunks-1", SchedulerChunks, 1},
+		{"cell", SchedulerPerCell, 0},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			w := newBenchWorld(b, c.scheduler, c.workers)
+			cellTurns := 0
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w.Step()
+				w.ForgetDeadOrganisms()
+				cellTurns += w.CellCount()
+				if w.CellCount() == 0 {
+					b.StopTimer()
+					w.SeedWorld(DefaultSeedChance, RandomGenomes{})
+					b.StartTimer()
+				}
+			}
+			b.ReportMetric(float64(cellTurns)/float64(b.N), "cells/turn")
+		})
+	}
+}
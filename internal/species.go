@@ -0,0 +1,79 @@
+package internal
+
+import "sync"
+
+// speciesClassifier clusters the living genomes around the genome which founded every species
+type speciesClassifier struct {
+	founders map[int][]uint8
+	// genomes holds the species of the genomes alive at the last update
+	genomes map[string]int
+	nextID  int
+	mx      sync.Mutex
+}
+
+func newSpeciesClassifier() *speciesClassifier {
+	return &speciesClassifier{founders: make(map[int][]uint8), genomes: make(map[string]int), nextID: 1}
+}
+
+func hammingDistance(a, b []uint8) int {
+	result := 0
+	for i := range a {
+		if a[i] != b[i] {
+			result += 1
+		}
+	}
+	return result
+}
+
+// classify prefers the species of the parent and falls back to the closest founder within the threshold
+func (s *speciesClassifier) classify(genome *Genome, threshold int) int {
+	if species, found := s.genomes[genome.parentID]; found {
+		if hammingDistance(s.founders[species], genome.genome) <= threshold {
+			return species
+		}
+	}
+	closest, closestDistance := 0, threshold+1
+	for species, founder := range s.founders {
+		distance := hammingDistance(founder, genome.genome)
+		if distance < closestDistance || distance == closestDistance && species < closest {
+			closest, closestDistance = species, distance
+		}
+	}
+	if closest != 0 {
+		return closest
+	}
+	closest = s.nextID
+	s.nextID += 1
+	s.founders[closest] = genome.genome
+	return closest
+}
+
+// update classifies the new genomes and forgets the extinct species
+func (s *speciesClassifier) update(w *World) map[string]int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	live := make(map[string]int)
+	w.cells.forEach(func(c *Cell, _ Position) {
+		if _, found := live[c.genomeID]; found {
+			return
+		}
+		species, found := s.genomes[c.genomeID]
+		if !found {
+			species = s.classify(w.GetGenome(c.genomeID), w.config.SpeciesThreshold)
+			// the genomes of this turn may be parents of the following ones
+			s.genomes[c.genomeID] = species
+		}
+		live[c.genomeID] = species
+	})
+	living := make(map[int]bool)
+	for _, species := range live {
+		living[species] = true
+	}
+	for species := range s.founders {
+		if !living[species] {
+			delete(s.founders, species)
+		}
+	}
+	s.genomes = live
+	return live
+}
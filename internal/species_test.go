@@ -0,0 +1,51 @@
+package internal
+
+import "testing"
+
+// speciesGenome differs from the zero genome in the given amount of leading genes
+func speciesGenome(id, parentID string, fill uint8, differing int) *Genome {
+	genes := make([]uint8, GenomeSize)
+	for i := 0; i < differing; i++ {
+		genes[i] = fill
+	}
+	return &Genome{id: id, genome: genes, parentID: parentID}
+}
+
+func TestSpeciesFollowTheFounders(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	genomes := []*Genome{
+		speciesGenome("founder", "", 0, 0),
+		speciesGenome("child", "founder", 1, SpeciesThreshold),
+		speciesGenome("stranger", "", 2, 3*SpeciesThreshold),
+		speciesGenome("relative", "", 2, 3*SpeciesThreshold-1),
+	}
+	var cells []*Cell
+	for i, genome := range genomes {
+		w.AddGenome(genome)
+		cell := NewCell(genome.id, CellTypeLeaf, Inventory{}, "o")
+		cells = append(cells, w.AddCell(Position{X: int64(2 * i), Y: 1}, cell))
+		// the genomes are classified one turn after another, the way they appear in the world
+		w.species.update(w)
+	}
+	species := w.species.update(w)
+	if species["child"] != species["founder"] {
+		t.Fatal("a child within the threshold left the species of its parent")
+	}
+	if species["stranger"] == species["founder"] {
+		t.Fatal("a distant genome joined the species of the founder")
+	}
+	if species["relative"] != species["stranger"] {
+		t.Fatal("a genome close to a founder did not join its species")
+	}
+
+	w.killCell(cells[2], DeathCauseEnergy)
+	w.killCell(cells[3], DeathCauseEnergy)
+	extinct := species["stranger"]
+	w.species.update(w)
+	returning := speciesGenome("returning", "", 2, 3*SpeciesThreshold)
+	w.AddGenome(returning)
+	w.AddCell(Position{X: 20, Y: 1}, NewCell(returning.id, CellTypeLeaf, Inventory{}, "p"))
+	if species := w.species.update(w); species["returning"] == extinct || species["returning"] == species["founder"] {
+		t.Fatalf("the returning genome joined species %d, the extinct one was %d", species["returning"], extinct)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+// transportDivider keeps the flow below the difference of the neighbours, a cell has up to four of them
+const transportDivider = 5
+
+// cellThroughput is the amount of transfer steps a cell of the type passes on per turn
+func cellThroughput(ct CellType) int {
+	switch ct {
+	case CellTypeTrunk, CellTypeConnector:
+		return 4
+	case CellTypeLeaf, CellTypeRoot:
+		return 2
+	case CellTypeSeed, CellTypeSprout:
+		return 1
+	case CellTypeFlower:
+		// flowers only receive resources
+		return 0
+	}
+	panic(ct)
+}
+
+// transportNetwork connects the neighbouring cells which may pass resources to each other, across
+// connectors it spans several organisms
+type transportNetwork struct {
+	cells []*Cell
+	// throughput is in transfer steps per item, the rates of the connectors come from their genes
+	throughput [][MaxItemType]int
+	// edges are pairs of cell indexes, resources flow from the first cell to the second one
+	edges [][2]int
+	// foreign marks the edges between different organisms
+	foreign []bool
+}
+
+func newTransportNetwork(w *World) *transportNetwork {
+	n := &transportNetwork{cells: make([]*Cell, 0, w.cells.count)}
+	index := make(map[Position]int, w.cells.count)
+	// the cells go by id so the edges and their budgets are used in the same order every run
+	for _, cell := range w.sortedCells() {
+		index[w.GetPosition(cell)] = len(n.cells)
+		n.cells = append(n.cells, cell)
+		var throughput [MaxItemType]int
+		if cell.cellType == CellTypeConnector {
+			_, throughput = w.connectorGenes(cell)
+		} else {
+			for it := range throughput {
+				throughput[it] = cellThroughput(cell.cellType)
+			}
+		}
+		n.throughput = append(n.throughput, throughput)
+	}
+	for i, cell := range n.cells {
+		if cellThroughput(cell.cellType) == 0 {
+			continue
+		}
+		ns := w.Neighbours(w.GetPosition(cell))
+		for k := range ns {
+			j, found := index[ns[k]]
+			if found && canTransfer(w, cell, n.cells[j]) {
+				n.edges = append(n.edges, [2]int{i, j})
+				n.foreign = append(n.foreign, cell.organismID != n.cells[j].organismID)
+			}
+		}
+	}
+	return n
+}
+
+// flow diffuses the item down the gradient one hop per pass, the totals are conserved and every cell
+// passes on at most its throughput, it returns the transported amount and the part of it which went to
+// foreign organisms
+func (n *transportNetwork) flow(it ItemType, passes int) (int64, int64) {
+	step := int(itemSpreadStep(it))
+	if step == 0 || len(n.edges) == 0 {
+		return 0, 0
+	}
+	limit := int((&CellInventory{}).maxForItemType(it))
+	initial := make([]int, len(n.cells))
+	budgets := make([]int, len(n.cells))
+	for i := range n.cells {
+		initial[i] = int(n.cells[i].GetFromInventory(it))
+		budgets[i] = step * n.throughput[i][it]
+	}
+	amounts := append([]int(nil), initial...)
+	gradient := make([]int, len(n.cells))
+	transported, shared := int64(0), int64(0)
+	for pass := 0; pass < passes; pass++ {
+		// the gradient of the previous pass limits every pass to a single hop
+		copy(gradient, amounts)
+		moved := false
+		for e, edge := range n.edges {
+			from, to := edge[0], edge[1]
+			amount := min((gradient[from]-gradient[to])/transportDivider, budgets[from], limit-amounts[to])
+			if amount <= 0 {
+				continue
+			}
+			amounts[from] -= amount
+			amounts[to] += amount
+			budgets[from] -= amount
+			transported += int64(amount)
+			if n.foreign[e] {
+				shared += int64(amount)
+			}
+			moved = true
+		}
+		if !moved {
+			break
+		}
+	}
+	for i := range n.cells {
+		if amounts[i] != initial[i] {
+			n.cells[i].AddToInventory(it, int16(amounts[i]-initial[i]))
+		}
+	}
+	return transported, shared
+}
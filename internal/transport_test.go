@@ -0,0 +1,133 @@
+package internal
+
+import "testing"
+
+type transportCell struct {
+	x, y      int64
+	cellType  CellType
+	organism  string
+	inventory Inventory
+	mode      ConnectorMode
+	rates     uint8
+}
+
+// addTransportCell places the cell with a genome holding its connector genes at the start
+func addTransportCell(w *World, c transportCell) *Cell {
+	genes := make([]uint8, GenomeSize)
+	genes[1], genes[2] = uint8(c.mode), c.rates
+	genome, err := NewGenomeFromGenes(genes)
+	if err != nil {
+		panic(err)
+	}
+	w.AddGenome(genome)
+	return w.AddCell(Position{X: c.x, Y: c.y}, NewCell(genome.id, c.cellType, c.inventory, c.organism))
+}
+
+func totals(w *World) Inventory {
+	var result Inventory
+	w.cells.forEach(func(c *Cell, _ Position) {
+		for it := ItemType(0); it < MaxItemType; it++ {
+			result[it] += c.GetFromInventory(it)
+		}
+	})
+	return result
+}
+
+func TestSpreadEnergyConservesResources(t *testing.T) {
+	full := Inventory{ItemTypeEnergy: 1000, ItemTypeWater: 400}
+	empty := Inventory{}
+	// all the rates are 3 transfer steps per item
+	const allRates = 0x3f
+	for _, c := range []struct {
+		name  string
+		cells []transportCell
+		// moves is whether any resource is expected to move
+		moves bool
+		// unchanged are the indexes of the cells which keep their inventory
+		unchanged []int
+	}{
+		{"isolated cell", []transportCell{{10, 10, CellTypeTrunk, "a", full, 0, 0}}, false, []int{0}},
+		{"chain", []transportCell{
+			{10, 10, CellTypeLeaf, "a", full, 0, 0},
+			{11, 10, CellTypeTrunk, "a", empty, 0, 0},
+			{12, 10, CellTypeTrunk, "a", empty, 0, 0},
+			{13, 10, CellTypeRoot, "a", empty, 0, 0},
+		}, true, nil},
+		{"branches", []transportCell{
+			{10, 10, CellTypeTrunk, "a", full, 0, 0},
+			{11, 10, CellTypeLeaf, "a", empty, 0, 0},
+			{9, 10, CellTypeRoot, "a", Inventory{ItemTypeWater: 400}, 0, 0},
+			{10, 11, CellTypeTrunk, "a", empty, 0, 0},
+			{10, 12, CellTypeFlower, "a", empty, 0, 0},
+			{10, 9, CellTypeSprout, "a", empty, 0, 0},
+		}, true, nil},
+		{"across the edge of the world", []transportCell{
+			{0, 5, CellTypeTrunk, "a", full, 0, 0},
+			{WorldSize - 1, 5, CellTypeTrunk, "a", empty, 0, 0},
+		}, true, nil},
+		{"flower passes nothing on", []transportCell{
+			{10, 10, CellTypeFlower, "a", full, 0, 0},
+			{11, 10, CellTypeTrunk, "a", empty, 0, 0},
+		}, false, []int{0, 1}},
+		{"connector without rates", []transportCell{
+			{10, 10, CellTypeConnector, "a", full, ConnectorShare, 0},
+			{11, 10, CellTypeTrunk, "b", empty, 0, 0},
+		}, false, []int{0, 1}},
+		{"closed connector", []transportCell{
+			{10, 10, CellTypeTrunk, "a", full, 0, 0},
+			{11, 10, CellTypeConnector, "a", empty, ConnectorClosed, allRates},
+			{12, 10, CellTypeTrunk, "b", empty, 0, 0},
+		}, true, []int{2}},
+		{"sharing connector", []transportCell{
+			{10, 10, CellTypeTrunk, "a", full, 0, 0},
+			{11, 10, CellTypeConnector, "a", empty, ConnectorShare, allRates},
+			{12, 10, CellTypeTrunk, "b", empty, 0, 0},
+			{13, 10, CellTypeLeaf, "b", empty, 0, 0},
+		}, true, nil},
+		{"vampire connector", []transportCell{
+			{10, 10, CellTypeConnector, "a", empty, ConnectorVampire, allRates},
+			{11, 10, CellTypeLeaf, "b", full, 0, 0},
+			{10, 11, CellTypeRoot, "c", full, 0, 0},
+			{9, 10, CellTypeTrunk, "a", empty, 0, 0},
+		}, true, nil},
+		{"vampires of each other", []transportCell{
+			{10, 10, CellTypeConnector, "a", full, ConnectorVampire, allRates},
+			{11, 10, CellTypeConnector, "b", empty, ConnectorVampire, allRates},
+			{12, 10, CellTypeTrunk, "b", Inventory{ItemTypeWater: 400}, 0, 0},
+		}, true, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := NewSeededWorld(WorldSize, 1)
+			cells := make([]*Cell, len(c.cells))
+			initial := make([]Inventory, len(c.cells))
+			for i := range c.cells {
+				cells[i] = addTransportCell(w, c.cells[i])
+				initial[i] = c.cells[i].inventory
+			}
+			before := totals(w)
+			w.SpreadEnergy()
+			if after := totals(w); after != before {
+				t.Fatalf("totals changed from %v to %v", before, after)
+			}
+			moved := false
+			for i, cell := range cells {
+				if cell.inventory != initial[i] {
+					moved = true
+				}
+				for it := ItemType(0); it < MaxItemType; it++ {
+					if cell.GetFromInventory(it) < 0 {
+						t.Fatalf("cell %d has a negative %v amount", i, it)
+					}
+				}
+			}
+			if moved != c.moves {
+				t.Fatalf("expected resources to move: %v, moved: %v", c.moves, moved)
+			}
+			for _, i := range c.unchanged {
+				if cells[i].inventory != initial[i] {
+					t.Fatalf("cell %d changed from %v to %v", i, initial[i], cells[i].inventory)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package internal
+
+import "sort"
+
+// cellView is the state of a neighbour as it was before a parallel phase
+type cellView struct {
+	cell      *Cell
+	cellType  CellType
+	registers [RegisterCount]uint8
+}
+
+// worldView is the read only state of the previous phase which the cells look at while running in
+// parallel, every cell only writes to itself and to the buffered requests of the world. The tiles do not
+// change within a parallel phase, the cell types and registers are copied because the cells change them
+type worldView struct {
+	world     *World
+	cellTypes []CellType
+	registers [][RegisterCount]uint8
+}
+
+// update copies the cell types into the buffer of the previous phase
+func (v *worldView) update(w *World) {
+	v.world = w
+	if len(v.cellTypes) != len(w.tiles) {
+		v.cellTypes = make([]CellType, len(w.tiles))
+		v.registers = make([][RegisterCount]uint8, len(w.tiles))
+	}
+	w.cells.forEach(func(cell *Cell, pos Position) {
+		i := w.tileIndex(pos)
+		v.cellTypes[i] = cell.cellType
+		v.registers[i] = cell.registers
+	})
+}
+
+func (v *worldView) at(pos Position) (cellView, bool) {
+	i := v.world.tileIndex(pos)
+	if v.world.tiles[i] == nil {
+		return cellView{}, false
+	}
+	return cellView{cell: v.world.tiles[i], cellType: v.cellTypes[i], registers: v.registers[i]}, true
+}
+
+func (v *worldView) occupied(pos Position) bool {
+	return v.world.Occupied(pos)
+}
+
+// drainRequest is a soil extraction asked for during a parallel phase, the cell gets it as the target item
+type drainRequest struct {
+	cell         *Cell
+	from, target ItemType
+	amount       int16
+}
+
+func (w *World) requestDrain(c *Cell, from, target ItemType, amount int16) {
+	w.drainRequestsMx.Lock()
+	w.drainRequests = append(w.drainRequests, drainRequest{cell: c, from: from, target: target, amount: amount})
+	w.drainRequestsMx.Unlock()
+}
+
+// applyDrains extracts the requested soil in cell order, so the neighbouring roots share it the same way
+// in every run
+func (w *World) applyDrains() {
+	sort.SliceStable(w.drainRequests, func(i, j int) bool {
+		return w.drainRequests[i].cell.id < w.drainRequests[j].cell.id
+	})
+	for _, r := range w.drainRequests {
+		got := w.DrainSquare(w.GetPosition(r.cell), r.from, r.amount)
+		r.cell.AddToInventory(r.target, got)
+	}
+	w.drainRequests = w.drainRequests[:0]
+}
+
+// pendingEvent is emitted by a cell during a parallel phase and held back until the phase is over
+type pendingEvent struct {
+	position Position
+	event    Event
+}
+
+// emitLater buffers the event of the cell, the events of every cell keep their order
+func (w *World) emitLater(c *Cell, e Event) {
+	w.pendingEventsMx.Lock()
+	w.pendingEvents = append(w.pendingEvents, pendingEvent{position: w.GetPosition(c), event: e})
+	w.pendingEventsMx.Unlock()
+}
+
+// flushEvents emits the buffered events by the positions of their cells, so the workers do not change the order
+func (w *World) flushEvents() {
+	sort.SliceStable(w.pendingEvents, func(i, j int) bool {
+		return positionLess(w.pendingEvents[i].position, w.pendingEvents[j].position)
+	})
+	for _, p := range w.pendingEvents {
+		w.events.Emit(p.event)
+	}
+	w.pendingEvents = w.pendingEvents[:0]
+}
+
+// sortedCells returns the cells by id, the order in which the random draws and conflicts are decided
+func (w *World) sortedCells() []*Cell {
+	cells := make([]*Cell, 0, w.cells.count)
+	w.cells.forEach(func(cell *Cell, _ Position) {
+		cells = append(cells, cell)
+	})
+	sort.Slice(cells, func(i, j int) bool {
+		return cells[i].id < cells[j].id
+	})
+	return cells
+}
+
+// startParallelPhase freezes the view and gives every cell its own random source split in cell order
+func (w *World) startParallelPhase() []*Cell {
+	w.view.update(w)
+	cells := w.sortedCells()
+	for _, cell := range cells {
+		cell.random = w.random.Split()
+	}
+	return cells
+}
@@ -1,7 +1,8 @@
 package internal
 
 import (
-	"fmt"
+	"encoding/json"
+	"sort"
 	"sync"
 	"time"
 )
@@ -10,6 +11,7 @@ type Position struct {
 	X, Y int64
 }
 
+// NewPosition wraps around the default WorldSize, worlds of other sizes wrap with World.NewPosition
 func NewPosition(x, y int64) Position {
 	return Position{X: (WorldSize + x) % WorldSize, Y: (WorldSize + y) % WorldSize}
 }
@@ -18,42 +20,20 @@ func (p Position) Equal(other Position) bool {
 	return p.X == other.X && p.Y == other.Y
 }
 
-func (p Position) Neighbours() []Position {
-	return []Position{
-		p.MovedByDirection(DirectionWest),
-		p.MovedByDirection(DirectionNorth),
-		p.MovedByDirection(DirectionEast),
-		p.MovedByDirection(DirectionSouth),
-	}
-}
-
-func (p Position) MovedByDirection(direction Direction) Position {
-	x, y := p.X, p.Y
-	switch direction {
-	case DirectionWest:
-		x -= 1
-	case DirectionEast:
-		x += 1
-	case DirectionNorth:
-		y -= 1
-	case DirectionSouth:
-		y += 1
-	}
-	return NewPosition(x, y)
-}
-
 type WorldExport struct {
 	cellTypes     map[Position]CellType
 	energy, water map[Position]int16
 	turn          int
 	organisms     map[Position]string
 	genomes       map[Position]string
+	species       map[Position]int
+	metrics       TurnMetrics
 }
 
 func NewWorldExport() WorldExport {
 	return WorldExport{
 		cellTypes: make(map[Position]CellType), energy: make(map[Position]int16), organisms: make(map[Position]string),
-		genomes: make(map[Position]string), water: make(map[Position]int16),
+		genomes: make(map[Position]string), water: make(map[Position]int16), species: make(map[Position]int),
 	}
 }
 
@@ -73,6 +53,10 @@ func (e *WorldExport) Genomes() map[Position]string {
 	return e.genomes
 }
 
+func (e *WorldExport) Species() map[Position]int {
+	return e.species
+}
+
 func (e *WorldExport) Turn() int {
 	return e.turn
 }
@@ -81,10 +65,70 @@ func (e *WorldExport) Water() map[Position]int16 {
 	return e.water
 }
 
+func (e *WorldExport) Metrics() TurnMetrics {
+	return e.metrics
+}
+
+type exportedCell struct {
+	Position Position `json:"position"`
+	CellType CellType `json:"cell_type"`
+	Energy   int16    `json:"energy"`
+	Water    int16    `json:"water"`
+	Organism string   `json:"organism"`
+	Genome   string   `json:"genome"`
+	Species  int      `json:"species"`
+}
+
+func (e WorldExport) MarshalJSON() ([]byte, error) {
+	cells := make([]exportedCell, 0, len(e.cellTypes))
+	for pos := range e.cellTypes {
+		cells = append(cells, exportedCell{
+			Position: pos, CellType: e.cellTypes[pos], Energy: e.energy[pos], Water: e.water[pos],
+			Organism: e.organisms[pos], Genome: e.genomes[pos], Species: e.species[pos],
+		})
+	}
+	return json.Marshal(struct {
+		Turn    int            `json:"turn"`
+		Cells   []exportedCell `json:"cells"`
+		Metrics TurnMetrics    `json:"metrics"`
+	}{Turn: e.turn, Cells: cells, Metrics: e.metrics})
+}
+
+func (e *WorldExport) clone() WorldExport {
+	result := NewWorldExport()
+	for pos := range e.cellTypes {
+		result.cellTypes[pos] = e.cellTypes[pos]
+		result.energy[pos] = e.energy[pos]
+		result.organisms[pos] = e.organisms[pos]
+		result.genomes[pos] = e.genomes[pos]
+		result.water[pos] = e.water[pos]
+		result.species[pos] = e.species[pos]
+	}
+	result.turn = e.turn
+	result.metrics = e.metrics
+	return result
+}
+
+func (e *WorldExport) remove(pos Position) {
+	delete(e.cellTypes, pos)
+	delete(e.energy, pos)
+	delete(e.organisms, pos)
+	delete(e.genomes, pos)
+	delete(e.water, pos)
+	delete(e.species, pos)
+}
+
+func (e *WorldExport) changedAt(another *WorldExport, pos Position) bool {
+	ct, found := e.cellTypes[pos]
+	return !found || ct != another.cellTypes[pos] || e.energy[pos] != another.energy[pos] ||
+		e.water[pos] != another.water[pos] || e.organisms[pos] != another.organisms[pos] ||
+		e.genomes[pos] != another.genomes[pos] || e.species[pos] != another.species[pos]
+}
+
 type World struct {
 	GenomeStorage
-	cellPositions map[*Cell]Position
-	size          int64
+	cells cellStore
+	size  int64
 
 	moveAttempts []*Cell
 	newCells     map[*Cell]Position
@@ -92,49 +136,117 @@ type World struct {
 	newCellsMx  sync.Mutex
 	movesMx     sync.Mutex
 	inventoryMx sync.Mutex
-	organisms   map[string][]string
+	organisms   map[string]*Organism
 	turn        int
-	inventory   map[Position]Inventory
+	metrics     TurnMetrics
+	metricsMx   sync.Mutex
+	events      EventBus
+	config      Config
+	random      *Random
+	species     *speciesClassifier
+	activity    *activityTracker
+	// changedOrganisms lost or moved cells during the turn and may be split
+	changedOrganisms map[string]bool
+	newCellParents   map[*Cell]uint64
+	lastCellID       uint64
+	// view is what the cells see of each other during the parallel phases
+	view            worldView
+	drainRequests   []drainRequest
+	drainRequestsMx sync.Mutex
+	pendingEvents   []pendingEvent
+	pendingEventsMx sync.Mutex
+	// tiles holds the cell and soil the inventory of every tile, both are indexed by tileIndex
+	tiles []*Cell
+	soil  []Inventory
+}
+
+func (w *World) Random() *Random {
+	return w.random
 }
 
 func (w *World) DrainSquare(pos Position, itemType ItemType, valuePerPos int16) int16 {
 	w.inventoryMx.Lock()
 	defer w.inventoryMx.Unlock()
-	ns := pos.Neighbours()
-	allPositions := append(ns, pos)
+	ns := w.Neighbours(pos)
+	allPositions := append(ns[:], pos)
 	totalGot := int16(0)
 	for i := range allPositions {
-		extraction := min(w.inventory[allPositions[i]][itemType], valuePerPos)
+		soil := &w.soil[w.tileIndex(allPositions[i])]
+		extraction := min(soil[itemType], valuePerPos)
 		totalGot += extraction
-		w.inventory[allPositions[i]][itemType] -= extraction
+		soil[itemType] -= extraction
 	}
 	return totalGot
 }
 
-func (w *World) GetCellByPosition(pos Position) *Cell {
-	for ix := range w.cellPositions {
-		if w.cellPositions[ix] == pos {
-			return ix
-		}
+func (w *World) Size() int64 {
+	return w.size
+}
+
+// NewPosition wraps the coordinates around the edges of the world
+func (w *World) NewPosition(x, y int64) Position {
+	return Position{X: (x%w.size + w.size) % w.size, Y: (y%w.size + w.size) % w.size}
+}
+
+// MovedByDirection returns the neighbouring tile, wrapping around the edges of the world
+func (w *World) MovedByDirection(pos Position, direction Direction) Position {
+	switch direction {
+	case DirectionWest:
+		pos.X -= 1
+	case DirectionEast:
+		pos.X += 1
+	case DirectionNorth:
+		pos.Y -= 1
+	case DirectionSouth:
+		pos.Y += 1
+	}
+	if pos.X < 0 {
+		pos.X += w.size
+	} else if pos.X >= w.size {
+		pos.X -= w.size
+	}
+	if pos.Y < 0 {
+		pos.Y += w.size
+	} else if pos.Y >= w.size {
+		pos.Y -= w.size
+	}
+	return pos
+}
+
+// Neighbours returns the neighbouring tiles in the order of the directions
+func (w *World) Neighbours(pos Position) [DirectionMax]Position {
+	var result [DirectionMax]Position
+	for d := Direction(0); d < DirectionMax; d++ {
+		result[d] = w.MovedByDirection(pos, d)
 	}
-	return nil
+	return result
+}
+
+func (w *World) tileIndex(pos Position) int {
+	return int(pos.Y*w.size + pos.X)
+}
+
+func (w *World) GetCellByPosition(pos Position) *Cell {
+	return w.tiles[w.tileIndex(pos)]
 }
 
-func (w *World) AddCell(pos Position, cell *Cell) {
-	w.cellPositions[cell] = pos
+// AddCell stores a copy of the cell on the tile and returns the stored cell
+func (w *World) AddCell(pos Position, cell *Cell) *Cell {
+	w.lastCellID += 1
+	cell.id = w.lastCellID
+	stored := w.cells.add(cell, pos)
+	w.tiles[w.tileIndex(pos)] = stored
+	w.addOrganismCell(stored)
+	return stored
 }
 
 func (w *World) Occupied(pos Position) bool {
-	for ix := range w.cellPositions {
-		if w.cellPositions[ix] == pos {
-			return true
-		}
-	}
-	return false
+	return w.tiles[w.tileIndex(pos)] != nil
 }
 
+// GetInventory returns a copy of the soil inventory
 func (w *World) GetInventory(pos Position) Inventory {
-	return w.inventory[pos]
+	return w.soil[w.tileIndex(pos)]
 }
 
 func (w *World) RegisterMove(c *Cell) {
@@ -146,141 +258,269 @@ func (w *World) RegisterMove(c *Cell) {
 func (w *World) CleanupTurn() {
 	w.moveAttempts = make([]*Cell, 0)
 	w.newCells = make(map[*Cell]Position)
+	w.newCellParents = make(map[*Cell]uint64)
 	w.newGenomes = make(map[string]*Genome)
 	w.turn += 1
-	for pos := range w.inventory {
-		w.inventory[pos][ItemTypeWater] = min(
-			WaterMaxAmount, w.inventory[pos][ItemTypeWater]+WaterRegenerationValue,
-		)
+	w.metrics = TurnMetrics{Turn: w.turn}
+	w.changedOrganisms = make(map[string]bool)
+	for i := range w.soil {
+		w.soil[i][ItemTypeWater] = min(WaterMaxAmount, w.soil[i][ItemTypeWater]+w.config.WaterRegenerationValue)
 	}
 }
 
 func (w *World) GetPosition(c *Cell) Position {
-	return w.cellPositions[c]
+	return w.cells.positions[c.index]
 }
 
-func (w *World) RegisterNewCell(cell *Cell, position Position, genome *Genome) {
+// RegisterNewCell spawns the cell at the end of the turn unless the position is taken
+func (w *World) RegisterNewCell(parent *Cell, cell *Cell, position Position, genome *Genome) {
 	w.newCellsMx.Lock()
 	w.newCells[cell] = position
+	w.newCellParents[cell] = parent.id
 	w.newGenomes[genome.id] = genome
 	w.newCellsMx.Unlock()
 }
 
+// Step runs all the phases of a turn
+func (w *World) Step() {
+	w.CleanupTurn()
+	w.ExecuteCellGenomes()
+	w.ExecuteTypeActions()
+	w.CreateNewCells()
+	w.SpreadEnergy()
+	w.DrainResources()
+	w.MoveCells()
+	w.RemoveCells()
+}
+
 func (w *World) ExecuteCellGenomes() {
-	var wg sync.WaitGroup
 	start := time.Now()
-	wg.Add(len(w.cellPositions))
-	for cell := range w.cellPositions {
-		go cell.ExecuteGenome(w, &wg)
-	}
-	wg.Wait()
-	print(fmt.Sprintf("Elapsed time thinking: %f\n", time.Now().Sub(start).Seconds()))
+	w.runCells(w.startParallelPhase(), func(c *Cell) {
+		c.ExecuteGenome(w)
+	})
+	w.flushEvents()
+	w.metrics.ThinkingTime = time.Since(start)
 }
 
 func (w *World) ExecuteTypeActions() {
-	var wg sync.WaitGroup
 	start := time.Now()
-	wg.Add(len(w.cellPositions))
-	for cell := range w.cellPositions {
-		go cell.ExecuteTypeAction(w, &wg)
-	}
-	wg.Wait()
-	print(fmt.Sprintf("Elapsed time type action: %f\n", time.Now().Sub(start).Seconds()))
+	w.runCells(w.startParallelPhase(), func(c *Cell) {
+		c.ExecuteTypeAction(w)
+	})
+	w.applyDrains()
+	w.flushEvents()
+	w.metrics.TypeActionTime = time.Since(start)
 }
 
+// MoveCells moves simultaneously, a move only succeeds into a tile which was free before the moves
 func (w *World) MoveCells() {
+	sort.Slice(w.moveAttempts, func(i, j int) bool {
+		return w.moveAttempts[i].id < w.moveAttempts[j].id
+	})
+	targets := make(map[Position][]contender)
 	for ix := range w.moveAttempts {
 		cell := w.moveAttempts[ix]
-		currentPosition := w.cellPositions[cell]
-		newPosition := currentPosition.MovedByDirection(cell.direction)
-		if w.Occupied(newPosition) {
-			present := w.GetCellByPosition(newPosition)
+		newPosition := w.MovedByDirection(w.GetPosition(cell), cell.direction)
+		if present := w.GetCellByPosition(newPosition); present != nil {
 			if cell.cellType == CellTypeSeed && present.cellType != CellTypeTrunk && present.cellType != CellTypeSeed {
 				present.AddToInventory(ItemTypeEnergy, -SeedSpawnEnergy)
 			}
 			continue
 		}
-
-		w.cellPositions[cell] = newPosition
+		targets[newPosition] = append(targets[newPosition], contender{cell: cell, order: cell.id})
+	}
+	for _, pos := range sortedPositions(targets) {
+		winner := w.resolveConflict(targets[pos])
+		for _, c := range targets[pos] {
+			if c.cell != winner {
+				w.emitConflictLost(c.cell, pos, "move")
+			}
+		}
+		if winner != nil {
+			w.tiles[w.tileIndex(w.GetPosition(winner))] = nil
+			w.tiles[w.tileIndex(pos)] = winner
+			w.cells.positions[winner.index] = pos
+			w.changedOrganisms[winner.organismID] = true
+		}
 	}
+	for ix := range w.moveAttempts {
+		cell := w.moveAttempts[ix]
+		if cell.landing {
+			cell.landing = false
+			w.emitSeedLanded(cell)
+		}
+	}
+}
+
+// emitSeedLanded reports a seed on the ground, after its flight or at once when it does not fly
+func (w *World) emitSeedLanded(c *Cell) {
+	w.events.Emit(SeedLanded{
+		EventHeader: w.header(EventSeedLanded), Position: w.GetPosition(c), Genome: c.genomeID,
+	})
 }
 
 func (w *World) CreateNewCells() {
+	newCells := make([]*Cell, 0, len(w.newCells))
 	for cell := range w.newCells {
+		newCells = append(newCells, cell)
+	}
+	// sorted so the blocked spawns are reported in the same order every run
+	sort.Slice(newCells, func(i, j int) bool {
+		a, b := newCells[i], newCells[j]
+		if w.newCells[a] != w.newCells[b] {
+			return positionLess(w.newCells[a], w.newCells[b])
+		}
+		if w.newCellParents[a] != w.newCellParents[b] {
+			return w.newCellParents[a] < w.newCellParents[b]
+		}
+		return a.direction < b.direction
+	})
+	targets := make(map[Position][]contender)
+	for _, cell := range newCells {
 		position := w.newCells[cell]
-		if w.Occupied(position) {
+		if occupant := w.GetCellByPosition(position); occupant != nil {
+			w.events.Emit(SpawnBlocked{
+				EventHeader: w.header(EventSpawnBlocked), Position: position, CellType: cell.cellType,
+				Organism: cell.organismID, Genome: cell.genomeID, Occupant: occupant.cellType,
+			})
+			continue
+		}
+		targets[position] = append(targets[position], contender{cell: cell, order: w.newCellParents[cell]})
+	}
+	for _, position := range sortedPositions(targets) {
+		cell := w.resolveConflict(targets[position])
+		for _, c := range targets[position] {
+			if c.cell != cell {
+				w.emitConflictLost(c.cell, position, "birth")
+			}
+		}
+		if cell == nil {
 			continue
 		}
 		w.GenomeStorage.AddGenome(w.newGenomes[cell.genomeID])
-		w.cellPositions[cell] = position
+		cell = w.AddCell(position, cell)
+		w.metrics.Births += 1
+		w.events.Emit(CellBorn{
+			EventHeader: w.header(EventCellBorn), Position: position, CellType: cell.cellType,
+			Organism: cell.organismID, Genome: cell.genomeID,
+		})
+		if cell.cellType == CellTypeSeed {
+			w.metrics.SeedsLaunched += 1
+			w.events.Emit(SeedLaunched{
+				EventHeader: w.header(EventSeedLaunched), Position: position, Genome: cell.genomeID,
+				FlyingTimer: cell.seedFlyingTimer,
+			})
+			if cell.seedFlyingTimer == 0 {
+				w.emitSeedLanded(cell)
+			}
+		}
 	}
 }
 
 func (w *World) Export() WorldExport {
 	result := NewWorldExport()
-	for cell, pos := range w.cellPositions {
+	result.metrics = w.Metrics()
+	w.species.mx.Lock()
+	defer w.species.mx.Unlock()
+	w.cells.forEach(func(cell *Cell, pos Position) {
 		result.cellTypes[pos] = cell.cellType
 		result.energy[pos] = cell.inventory[ItemTypeEnergy]
 		result.organisms[pos] = cell.organismID
 		result.genomes[pos] = cell.genomeID
 		result.water[pos] = cell.inventory[ItemTypeWater]
-	}
+		result.species[pos] = w.species.genomes[cell.genomeID]
+	})
 	result.turn = w.turn
 	return result
 }
 
 func (w *World) RemoveCells() {
-	for cell := range w.cellPositions {
+	for _, cell := range w.sortedCells() {
 		cell.age += 1
-		if cell.inventory[ItemTypeEnergy] <= 0 || cell.TooOld() || cell.inventory[ItemTypeWater] <= 0 {
-			cell.Die(w)
+		cause := MaxDeathCause
+		if cell.inventory[ItemTypeEnergy] <= 0 {
+			cause = DeathCauseEnergy
+		} else if cell.TooOld(w) {
+			cause = DeathCauseAge
+		} else if cell.inventory[ItemTypeWater] <= 0 {
+			cause = DeathCauseWater
 		}
+		if cause != MaxDeathCause {
+			w.killCell(cell, cause)
+		}
+	}
+	w.splitFragments(w.changedOrganisms)
+}
+
+func (w *World) killCell(cell *Cell, cause DeathCause) {
+	w.metrics.Deaths[cause] += 1
+	w.events.Emit(CellDied{
+		EventHeader: w.header(EventCellDied), Position: w.GetPosition(cell), CellType: cell.cellType,
+		Organism: cell.organismID, Genome: cell.genomeID, Cause: cause, Age: cell.age,
+	})
+	w.changedOrganisms[cell.organismID] = true
+	// the slot of the cell is cleared when it dies
+	organism := w.removeOrganismCell(cell)
+	cell.Die(w)
+	if organism != nil {
+		w.events.Emit(OrganismExtinct{
+			EventHeader: w.header(EventOrganismExtinct), Organism: organism.id, Founder: organism.genomeID,
+			BirthTurn: organism.birthTurn, PeakSize: organism.peakSize,
+		})
 	}
 }
 
 func (w *World) DrainResources() {
-	for cell := range w.cellPositions {
+	w.cells.forEach(func(cell *Cell, _ Position) {
 		cell.SpendEnergy(w)
 		cell.SpendWater(w)
-	}
+	})
 }
 
+// GetCells returns a snapshot of the living cells and their positions
 func (w *World) GetCells() map[*Cell]Position {
-	return w.cellPositions
+	result := make(map[*Cell]Position, w.cells.count)
+	w.cells.forEach(func(cell *Cell, pos Position) {
+		result[cell] = pos
+	})
+	return result
+}
+
+// CellCount returns the amount of living cells
+func (w *World) CellCount() int {
+	return w.cells.count
 }
 
 func (w *World) SpreadEnergy() {
-	var wg sync.WaitGroup
 	start := time.Now()
-	organisms := make(map[string]*Organism)
-	for ix := range w.GetCells() {
-		if _, found := organisms[ix.organismID]; !found {
-			organisms[ix.organismID] = &Organism{}
-		}
-		organisms[ix.organismID].RegisterCell(ix)
-	}
-	wg.Add(len(organisms))
-	for i := range organisms {
-		go organisms[i].HandleResourcesFlow(w, &wg)
+	network := newTransportNetwork(w)
+	for it := ItemType(0); it < MaxItemType; it++ {
+		w.metrics.Pulled[it] = w.pullByVampires(it)
+		w.metrics.Transported[it], w.metrics.Shared[it] = network.flow(it, w.config.TransportPasses)
 	}
-	wg.Wait()
-	print(fmt.Sprintf("Elapsed time spread energy: %f\n", time.Now().Sub(start).Seconds()))
+	w.diffuseMorphogens()
+	w.metrics.SpreadTime = time.Since(start)
 }
 
 func NewWorld(size int64) *World {
+	return NewSeededWorld(size, defaultRandom.Int63())
+}
+
+// NewSeededWorld creates a world which takes all the random decisions from the given seed
+func NewSeededWorld(size int64, seed int64) *World {
 	w := World{
-		size: size, cellPositions: make(map[*Cell]Position),
-		moveAttempts: make([]*Cell, 0), newCells: make(map[*Cell]Position), inventory: make(map[Position]Inventory),
-		GenomeStorage: NewGenomeStorage(),
+		size:         size,
+		moveAttempts: make([]*Cell, 0), newCells: make(map[*Cell]Position),
+		tiles: make([]*Cell, size*size), soil: make([]Inventory, size*size),
+		organisms: make(map[string]*Organism), changedOrganisms: make(map[string]bool),
+		newCellParents: make(map[*Cell]uint64), GenomeStorage: NewGenomeStorage(), config: DefaultConfig(),
+		random: NewRandom(seed), species: newSpeciesClassifier(), activity: newActivityTracker(seed),
 	}
-	for i := 0; i < WorldSize; i++ {
-		for j := 0; j < WorldSize; j++ {
-			pos := NewPosition(int64(i), int64(j))
-			w.inventory[pos] = Inventory{
-				ItemTypeWater:   WaterMaxAmount,
-				ItemTypeOrganic: StartingOrganicLevel,
-				ItemTypeEnergy:  MaxSunLevel,
-			}
+	for i := range w.soil {
+		w.soil[i] = Inventory{
+			ItemTypeWater:   WaterMaxAmount,
+			ItemTypeOrganic: StartingOrganicLevel,
+			ItemTypeEnergy:  MaxSunLevel,
 		}
 	}
 	return &w
@@ -0,0 +1,68 @@
+package internal
+
+import "testing"
+
+func TestWorldWrapsAroundItsOwnSize(t *testing.T) {
+	w := NewSeededWorld(1000, 1)
+	cases := []struct {
+		name      string
+		pos       Position
+		direction Direction
+		expected  Position
+	}{
+		{"west edge", Position{X: 0, Y: 5}, DirectionWest, Position{X: 999, Y: 5}},
+		{"east edge", Position{X: 999, Y: 5}, DirectionEast, Position{X: 0, Y: 5}},
+		{"north edge", Position{X: 5, Y: 0}, DirectionNorth, Position{X: 5, Y: 999}},
+		{"south edge", Position{X: 5, Y: 999}, DirectionSouth, Position{X: 5, Y: 0}},
+		{"beyond the default size", Position{X: 150, Y: 500}, DirectionEast, Position{X: 151, Y: 500}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if moved := w.MovedByDirection(c.pos, c.direction); moved != c.expected {
+				t.Fatalf("moved to %v, expected %v", moved, c.expected)
+			}
+		})
+	}
+	if pos := w.NewPosition(-1, 1000); pos != (Position{X: 999, Y: 0}) {
+		t.Fatalf("wrapped to %v", pos)
+	}
+}
+
+func TestLargeWorldKeepsCellsOnTheirTiles(t *testing.T) {
+	w := NewSeededWorld(1000, 1)
+	corner := NewCell("g", CellTypeLeaf, Inventory{ItemTypeEnergy: 10, ItemTypeWater: 10}, "o")
+	w.AddCell(Position{X: 999, Y: 999}, corner)
+	far := NewCell("g", CellTypeLeaf, Inventory{ItemTypeEnergy: 10, ItemTypeWater: 10}, "o")
+	w.AddCell(Position{X: 640, Y: 320}, far)
+
+	if w.GetCellByPosition(Position{X: 999, Y: 999}) == nil || w.GetCellByPosition(Position{X: 640, Y: 320}) == nil {
+		t.Fatal("cells are not found on their tiles")
+	}
+	if w.Occupied(Position{X: 99, Y: 99}) || w.Occupied(Position{X: 40, Y: 20}) {
+		t.Fatal("cells are found on the tiles of the default world size")
+	}
+	ns := w.Neighbours(Position{X: 0, Y: 999})
+	if !w.Occupied(ns[DirectionWest]) {
+		t.Fatal("the corner cell is not a neighbour across the edge")
+	}
+}
+
+func TestDeadCellSlotsAreReused(t *testing.T) {
+	w := NewSeededWorld(WorldSize, 1)
+	first := w.AddCell(Position{X: 1, Y: 1}, NewCell("g", CellTypeLeaf, Inventory{}, "o"))
+	second := w.AddCell(Position{X: 5, Y: 5}, NewCell("g", CellTypeLeaf, Inventory{}, "o"))
+	w.killCell(first, DeathCauseEnergy)
+	if w.Occupied(Position{X: 1, Y: 1}) || w.CellCount() != 1 {
+		t.Fatal("the dead cell is still in the world")
+	}
+	third := w.AddCell(Position{X: 7, Y: 3}, NewCell("g", CellTypeRoot, Inventory{}, "o"))
+	if third != first || third.index != 0 {
+		t.Fatal("the slot of the dead cell is not reused")
+	}
+	if w.GetPosition(third) != (Position{X: 7, Y: 3}) || w.GetPosition(second) != (Position{X: 5, Y: 5}) {
+		t.Fatal("the cells are not found on their tiles")
+	}
+	if cells := w.GetCells(); len(cells) != 2 || cells[third] != (Position{X: 7, Y: 3}) {
+		t.Fatalf("unexpected cells %v", cells)
+	}
+}
@@ -2,19 +2,23 @@ package main
 
 import (
 	"embed"
+	"flag"
 	"fmt"
-	"hash/fnv"
 	"image"
+	"image/draw"
 	_ "image/png"
+	"io"
 	"math"
-	"math/rand"
 	"multicell/internal"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
 	"github.com/faiface/pixel/pixelgl"
 	"github.com/faiface/pixel/text"
-	"github.com/google/uuid"
 	"golang.org/x/image/colornames"
 	"golang.org/x/image/font/basicfont"
 )
@@ -24,7 +28,7 @@ const SimulationSteps = 1000000
 //go:embed resources/*
 var resources embed.FS
 
-func loadPicture(path string) (pixel.Picture, error) {
+func loadImage(path string) (*image.RGBA, error) {
 	file, err := resources.Open(path)
 	if err != nil {
 		return nil, err
@@ -34,46 +38,14 @@ func loadPicture(path string) (pixel.Picture, error) {
 	if err != nil {
 		return nil, err
 	}
-	return pixel.PictureDataFromImage(img), nil
-}
-
-func runSimulationStep(world *internal.World) {
-	world.CleanupTurn()
-	world.ExecuteCellGenomes()
-	world.ExecuteTypeActions()
-	world.CreateNewCells()
-	world.SpreadEnergy()
-	world.DrainResources()
-	world.MoveCells()
-	world.RemoveCells()
-}
-
-func runSimulation(world *internal.World, exporter chan internal.WorldExport) {
-	noProgressCounter := 0
-	for i := 0; i < SimulationSteps; i++ {
-		runSimulationStep(world)
-		exporter <- world.Export()
-
-		canProgress := false
-		for c := range world.GetCells() {
-			if c.GetType() == internal.CellTypeSprout || c.GetType() == internal.CellTypeFlower {
-				canProgress = true
-			}
-		}
-		if !canProgress {
-			noProgressCounter += 1
-		}
-		if noProgressCounter >= 200 {
-			seedWorld(world)
-			noProgressCounter = 0
-		}
-
-	}
-	close(exporter)
+	result := image.NewRGBA(img.Bounds())
+	draw.Draw(result, result.Bounds(), img, img.Bounds().Min, draw.Src)
+	return result, nil
 }
 
 type Resources struct {
 	spritesheet pixel.Picture
+	sheetImage  *image.RGBA
 
 	framesMap map[internal.CellType]int
 	frames    []pixel.Rect
@@ -82,15 +54,16 @@ type Resources struct {
 func loadResources() *Resources {
 	result := Resources{}
 	var err error
-	result.spritesheet, err = loadPicture("resources/sprites/sheet_small.png")
+	result.sheetImage, err = loadImage("resources/sprites/sheet_small.png")
 	if err != nil {
-		panic(err)
+		exitWithError(err)
 	}
+	result.spritesheet = pixel.PictureDataFromImage(result.sheetImage)
 	result.frames = make([]pixel.Rect, 0)
 	result.framesMap = make(map[internal.CellType]int)
-	for x := result.spritesheet.Bounds().Min.X; x < result.spritesheet.Bounds().Max.X; x += 32 {
-		for y := result.spritesheet.Bounds().Min.Y; y < result.spritesheet.Bounds().Max.Y; y += 32 {
-			result.frames = append(result.frames, pixel.R(x, y, x+32, y+32))
+	for x := result.spritesheet.Bounds().Min.X; x < result.spritesheet.Bounds().Max.X; x += spriteSize {
+		for y := result.spritesheet.Bounds().Min.Y; y < result.spritesheet.Bounds().Max.Y; y += spriteSize {
+			result.frames = append(result.frames, pixel.R(x, y, x+spriteSize, y+spriteSize))
 		}
 	}
 	result.framesMap[internal.CellTypeFlower] = 0
@@ -103,7 +76,14 @@ func loadResources() *Resources {
 	return &result
 }
 
-func run(exporter chan internal.WorldExport) {
+func speedLabel(speed int) string {
+	if speed == MaxSpeed {
+		return "max"
+	}
+	return fmt.Sprintf("%dx", speed)
+}
+
+func run(runner *Runner) {
 	resources := loadResources()
 	cfg := pixelgl.WindowConfig{
 		Title:  "Multicell",
@@ -112,7 +92,7 @@ func run(exporter chan internal.WorldExport) {
 	}
 	win, err := pixelgl.NewWindow(cfg)
 	if err != nil {
-		panic(err)
+		exitWithError(err)
 	}
 
 	var (
@@ -123,39 +103,58 @@ func run(exporter chan internal.WorldExport) {
 	)
 
 	last := time.Now()
-	pause := true
-	oneStep := false
 	visionMode := 0
 	keySWasReleased, wasReleased := true, true
-	var worldExport internal.WorldExport
 	batch := pixel.NewBatch(&pixel.TrianglesData{}, resources.spritesheet)
-	more := true
+	timeline := imdraw.New(nil)
+	charts := NewStatsCharts(text.NewAtlas(basicfont.Face7x13, text.ASCII))
+	showCharts := false
+	// -1 means the latest turn is shown, otherwise the history is scrubbed
+	historyTurn := -1
 	for !win.Closed() {
 		if win.JustPressed(pixelgl.KeySpace) && wasReleased {
-			pause = !pause
+			runner.SetPaused(!runner.Paused())
 			wasReleased = false
 		}
 		if win.JustPressed(pixelgl.KeyS) && keySWasReleased {
 			keySWasReleased = false
-			oneStep = true
+			runner.Step()
+		}
+		if win.JustPressed(pixelgl.Key1) {
+			runner.SetSpeed(1)
+		}
+		if win.JustPressed(pixelgl.Key2) {
+			runner.SetSpeed(10)
+		}
+		if win.JustPressed(pixelgl.Key3) {
+			runner.SetSpeed(MaxSpeed)
+		}
+		if win.JustPressed(pixelgl.KeyEqual) && runner.Speed() != MaxSpeed {
+			runner.SetSpeed(runner.Speed() + 1)
+		}
+		if win.JustPressed(pixelgl.KeyMinus) && runner.Speed() > 1 {
+			runner.SetSpeed(runner.Speed() - 1)
+		}
+		if win.JustPressed(pixelgl.KeyY) {
+			visionMode = VisionModeSpecies
 		}
-		// TODO: at least make constants
 		if win.JustPressed(pixelgl.KeyT) {
-			// genome view
-			visionMode = 4
+			visionMode = VisionModeWater
 		}
 		if win.JustPressed(pixelgl.KeyR) {
-			// genome view
-			visionMode = 3
+			visionMode = VisionModeGenome
 		}
 		if win.JustPressed(pixelgl.KeyW) {
-			visionMode = 2
+			visionMode = VisionModeOrganism
 		}
 		if win.JustPressed(pixelgl.KeyE) {
-			visionMode = 1
+			visionMode = VisionModeEnergy
 		}
 		if win.JustPressed(pixelgl.KeyQ) {
-			visionMode = 0
+			visionMode = VisionModeCellType
+		}
+		if win.JustPressed(pixelgl.KeyC) {
+			showCharts = !showCharts
 		}
 
 		if win.JustReleased(pixelgl.KeyS) {
@@ -165,12 +164,46 @@ func run(exporter chan internal.WorldExport) {
 		if win.JustReleased(pixelgl.KeySpace) {
 			wasReleased = true
 		}
-		if (!pause || oneStep) && more {
-			oneStep = false
-			var tmpWE internal.WorldExport
-			tmpWE, more = <-exporter
-			if more {
-				worldExport = tmpWE
+		worldExport := runner.Latest()
+		if history := runner.History(); history != nil {
+			first, lastRecorded := history.Turns()
+			if historyTurn == -1 && (win.Pressed(pixelgl.KeyComma) || win.JustPressed(pixelgl.KeyLeftBracket)) {
+				historyTurn = worldExport.Turn()
+			}
+			if historyTurn != -1 {
+				if win.Pressed(pixelgl.KeyComma) {
+					historyTurn -= 1
+				}
+				if win.Pressed(pixelgl.KeyPeriod) {
+					historyTurn += 1
+				}
+				if win.JustPressed(pixelgl.KeyLeftBracket) {
+					historyTurn -= 100
+				}
+				if win.JustPressed(pixelgl.KeyRightBracket) {
+					historyTurn += 100
+				}
+				historyTurn = max(first, historyTurn)
+				if historyTurn >= lastRecorded || win.JustPressed(pixelgl.KeyL) {
+					historyTurn = -1
+				}
+			}
+			if historyTurn != -1 {
+				if recorded, found := history.At(historyTurn); found {
+					worldExport = recorded
+				}
+			}
+
+			timeline.Clear()
+			if lastRecorded > first {
+				bounds := win.Bounds()
+				timeline.Color = colornames.Gray
+				timeline.Push(pixel.V(20, 20), pixel.V(bounds.W()-20, 28))
+				timeline.Rectangle(0)
+				cursor := 20 + (bounds.W()-40)*float64(worldExport.Turn()-first)/float64(lastRecorded-first)
+				timeline.Color = colornames.White
+				timeline.Push(pixel.V(cursor-2, 14), pixel.V(cursor+2, 34))
+				timeline.Rectangle(0)
 			}
 		}
 
@@ -183,45 +216,16 @@ func run(exporter chan internal.WorldExport) {
 
 		cam := pixel.IM.Scaled(camPos, camZoom).Moved(win.Bounds().Center().Sub(camPos))
 		win.SetMatrix(cam)
-		// TODO: common sprite handler
 		for pos := range worldExport.CellTypes() {
-			rectNum := resources.framesMap[worldExport.CellTypes()[pos]]
-			if visionMode != 0 {
-				rectNum += 1
-			}
-			rect := resources.frames[rectNum]
+			rect := resources.spriteFrame(worldExport.CellTypes()[pos], visionMode)
 			cells = append(cells, pixel.NewSprite(resources.spritesheet, rect))
 			matrices = append(
 				matrices,
 				pixel.IM.Moved(
-					pixel.V(float64(pos.X)*32, float64(pos.Y)*32),
+					pixel.V(float64(pos.X)*spriteSize, float64(pos.Y)*spriteSize),
 				),
 			)
-			var color *pixel.RGBA
-			if visionMode == 1 {
-				color = &pixel.RGBA{R: 0.1 + 0.9*float64(worldExport.Energy()[pos])/float64(internal.MaxEnergy)}
-			} else if visionMode == 2 || visionMode == 3 {
-				source := worldExport.Organisms()[pos]
-				if visionMode == 3 {
-					source = worldExport.Genomes()[pos]
-				}
-
-				hash := fnv.New32a()
-				hash.Write([]byte(source))
-				hashValue := hash.Sum32()
-				minColorValue := 0.3
-				rgbDivider := 255 * (1.0 - minColorValue)
-				rgba := pixel.RGBA{
-					R: minColorValue + float64(hashValue&0xFF)/rgbDivider,
-					G: minColorValue + float64((hashValue>>8)&0xFF)/rgbDivider,
-					B: minColorValue + float64((hashValue>>16)&0xFF)/rgbDivider,
-					A: 255,
-				}
-				color = &rgba
-			} else if visionMode == 4 {
-				color = &pixel.RGBA{B: 0.1 + 0.9*float64(worldExport.Water()[pos])/float64(internal.WaterMaxAmount)}
-			}
-			colors = append(colors, color)
+			colors = append(colors, cellColorMask(&worldExport, pos, visionMode))
 		}
 
 		if win.Pressed(pixelgl.KeyLeft) {
@@ -253,55 +257,317 @@ func run(exporter chan internal.WorldExport) {
 
 		_, err = fmt.Fprintf(basicTxt, "%d\n", worldExport.Turn())
 		if err != nil {
-			panic(err)
+			exitWithError(err)
 		}
 
 		basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 4))
-		if pause {
+		statsTxt := text.New(camPos.Add(pixel.V(-300, -340)), basicAtlas)
+		_, err = fmt.Fprintf(
+			statsTxt, "speed %s, %.0f turns/s, frame %.1f ms\n",
+			speedLabel(runner.Speed()), runner.TurnsPerSecond(), dt*1000,
+		)
+		if err != nil {
+			exitWithError(err)
+		}
+		statsTxt.Draw(win, pixel.IM.Scaled(statsTxt.Orig, 2))
+		if historyTurn != -1 {
+			historyTxt := text.New(camPos.Add(pixel.V(-0, -340)), basicAtlas)
+			_, err = fmt.Fprintf(historyTxt, "HISTORY, L to go live\n")
+			if err != nil {
+				exitWithError(err)
+			}
+			historyTxt.Draw(win, pixel.IM.Scaled(historyTxt.Orig, 2))
+		}
+		if runner.Paused() {
 			basicTxt = text.New(camPos.Add(pixel.V(-0, -300)), basicAtlas)
 
 			_, err = fmt.Fprintf(basicTxt, "PAUSED\n")
 			if err != nil {
-				panic(err)
+				exitWithError(err)
 			}
 			basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 4))
 		}
+		win.SetMatrix(pixel.IM)
+		timeline.Draw(win)
+		if showCharts {
+			charts.Draw(win, win.Bounds(), runner.RecentMetrics(), worldExport.Turn())
+		}
 
 		win.Update()
 	}
 }
 
-func seedWorld(w *internal.World) {
-	for i := 0; i < internal.WorldSize; i++ {
-		for j := 0; j < internal.WorldSize; j++ {
-			pos := internal.NewPosition(int64(i), int64(j))
-			if rand.Float32() > 0.03 {
-				continue
-			}
-			parentGenome := internal.NewGenome(nil)
-			w.AddGenome(parentGenome)
-			w.AddCell(
-				pos,
-				internal.NewCell(
-					parentGenome.GetID(), internal.CellTypeSeed,
-					internal.Inventory{internal.ItemTypeWater: internal.WaterMaxAmount,
-						internal.ItemTypeEnergy: internal.MaxEnergy},
-					uuid.NewString(),
-				),
-			)
+func loadHistory(path string) *internal.History {
+	file, err := os.Open(path)
+	if err != nil {
+		exitWithError(err)
+	}
+	defer file.Close()
+	history, err := internal.LoadHistory(file)
+	if err != nil {
+		exitWithError(err)
+	}
+	return history
+}
+
+// openMetricsSink picks the columnar JSON format for .json files and CSV otherwise
+func openMetricsSink(path string) internal.MetricsSink {
+	file, err := os.Create(path)
+	if err != nil {
+		exitWithError(err)
+	}
+	if strings.HasSuffix(path, ".json") {
+		return internal.NewMetricsColumnsWriter(file)
+	}
+	return internal.NewMetricsCSVWriter(file)
+}
 
-			//return
+// simulationOptions are the flags shared by the commands running a simulation
+type simulationOptions struct {
+	historySize      int
+	keyframeInterval int
+	recordPath       string
+	replayPath       string
+	metricsPath      string
+	eventsPath       string
+	reseed           string
+	reseedGenomes    string
+	saveLibraryPath  string
+	hallOfFamePath   string
+	fitness          string
+	hallOfFameSize   int
+}
+
+func (o *simulationOptions) register(flags *flag.FlagSet) {
+	flags.IntVar(&o.historySize, "history", 1000, "amount of turns kept for rewinding")
+	flags.IntVar(&o.keyframeInterval, "keyframes", 100, "amount of turns between history keyframes")
+	flags.StringVar(&o.recordPath, "record", "", "file to record the whole simulation history to")
+	flags.StringVar(&o.replayPath, "replay", "", "recorded history file to replay instead of simulating")
+	flags.StringVar(&o.metricsPath, "metrics", "", "file to write per turn metrics to, CSV or columnar .json")
+	flags.StringVar(&o.eventsPath, "events", "", "file to write the simulation events to as JSON lines")
+	flags.StringVar(
+		&o.reseed, "reseed", fmt.Sprintf("extinction:%d", DefaultReseedTurns),
+		"reseed policy: never, extinction[:turns] or immigration[:interval[:amount]]",
+	)
+	flags.StringVar(
+		&o.reseedGenomes, "reseed-genomes", "random",
+		"genomes of the planted seeds: random, library:path, top:N genomes with the most births or hall of fame",
+	)
+	flags.StringVar(&o.saveLibraryPath, "save-library", "", "file to save the living genomes to on exit")
+	flags.StringVar(
+		&o.hallOfFamePath, "hall-of-fame", "", "hall of fame file to continue if it exists and to save on exit",
+	)
+	flags.StringVar(&o.fitness, "fitness", "lineage", "hall of fame fitness: size, seeds or lineage")
+	flags.IntVar(&o.hallOfFameSize, "hall-of-fame-size", 50, "amount of genomes kept in the hall of fame")
+}
+
+// hallOfFame returns nil when neither the file nor the reseeding asks for it
+func (o *simulationOptions) hallOfFame() (*internal.HallOfFame, error) {
+	if o.hallOfFamePath == "" && o.reseedGenomes != "hall" {
+		return nil, nil
+	}
+	fitness, err := internal.ParseFitness(o.fitness)
+	if err != nil {
+		return nil, err
+	}
+	if o.hallOfFamePath == "" {
+		return internal.NewHallOfFame(fitness, o.hallOfFameSize), nil
+	}
+	file, err := os.Open(o.hallOfFamePath)
+	if os.IsNotExist(err) {
+		return internal.NewHallOfFame(fitness, o.hallOfFameSize), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	hallOfFame, err := internal.LoadHallOfFame(file, o.hallOfFameSize)
+	if err != nil {
+		return nil, err
+	}
+	hallOfFame.SetFitness(fitness)
+	return hallOfFame, nil
+}
+
+func saveHallOfFame(path string, hallOfFame *internal.HallOfFame) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := hallOfFame.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// reseedPolicy parses the policy and genome source flags, the source also seeds a new world
+func (o *simulationOptions) reseedPolicy(
+	world *internal.World, hallOfFame *internal.HallOfFame,
+) (internal.ReseedPolicy, internal.GenomeSource, error) {
+	var source internal.GenomeSource = internal.RandomGenomes{}
+	kind, argument, _ := strings.Cut(o.reseedGenomes, ":")
+	switch kind {
+	case "random":
+	case "hall":
+		source = hallOfFame
+	case "library":
+		file, err := os.Open(argument)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer file.Close()
+		genomes, err := internal.LoadGenomeLibrary(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		source = internal.NewGenomeLibrary(genomes)
+	case "top":
+		n, err := strconv.Atoi(argument)
+		if err != nil {
+			return nil, nil, fmt.Errorf("top genomes amount: %w", err)
 		}
+		source = internal.NewTopGenomes(world, n)
+	default:
+		return nil, nil, fmt.Errorf("unknown reseed genomes %q", o.reseedGenomes)
 	}
+
+	parts := strings.Split(o.reseed, ":")
+	numbers := make([]int, len(parts)-1)
+	for i := range numbers {
+		var err error
+		if numbers[i], err = strconv.Atoi(parts[i+1]); err != nil {
+			return nil, nil, fmt.Errorf("reseed policy %q: %w", o.reseed, err)
+		}
+	}
+	number := func(i, fallback int) int {
+		if i < len(numbers) {
+			return numbers[i]
+		}
+		return fallback
+	}
+	switch parts[0] {
+	case "never":
+		return internal.NeverReseed{}, source, nil
+	case "extinction":
+		return internal.NewExtinctionReseed(number(0, DefaultReseedTurns), source), source, nil
+	case "immigration":
+		return internal.NewImmigrationReseed(number(0, 100), number(1, 10), source), source, nil
+	}
+	return nil, nil, fmt.Errorf("unknown reseed policy %q", o.reseed)
 }
 
-func main() {
+func saveGenomeLibrary(path string, genomes []*internal.Genome) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := internal.WriteGenomeLibrary(file, genomes); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// newRunner returns the runner and a function stopping it and closing every output
+func (o *simulationOptions) newRunner() (*Runner, func()) {
+	if o.replayPath != "" {
+		runner := NewReplayRunner(loadHistory(o.replayPath))
+		return runner, runner.Stop
+	}
+
+	var closers []func() error
 	world := internal.NewWorld(internal.WorldSize)
-	seedWorld(world)
-	exporter := make(chan internal.WorldExport)
-	go runSimulation(world, exporter)
+	if o.eventsPath != "" {
+		file, err := os.Create(o.eventsPath)
+		if err != nil {
+			exitWithError(err)
+		}
+		events := internal.NewJSONLinesSink(file)
+		world.Events().Subscribe(events.Handle)
+		closers = append(closers, events.Flush, file.Close)
+	}
+	var sink io.Writer
+	if o.recordPath != "" {
+		file, err := os.Create(o.recordPath)
+		if err != nil {
+			exitWithError(err)
+		}
+		closers = append(closers, file.Close)
+		sink = file
+	}
+	hallOfFame, err := o.hallOfFame()
+	if err != nil {
+		exitWithError(err)
+	}
+	reseed, source, err := o.reseedPolicy(world, hallOfFame)
+	if err != nil {
+		exitWithError(err)
+	}
+	// sources collecting genomes during the run have nothing to offer yet
+	if world.SeedWorld(internal.DefaultSeedChance, source) == 0 {
+		world.SeedWorld(internal.DefaultSeedChance, internal.RandomGenomes{})
+	}
+	runner := NewRunner(world, internal.NewHistory(o.historySize, o.keyframeInterval, sink))
+	runner.reseed = reseed
+	if hallOfFame != nil {
+		hallOfFame.Attach(world)
+		runner.hallOfFame = hallOfFame
+		if o.hallOfFamePath != "" {
+			closers = append(closers, func() error {
+				return saveHallOfFame(o.hallOfFamePath, hallOfFame)
+			})
+		}
+	}
+	if o.metricsPath != "" {
+		runner.metrics = openMetricsSink(o.metricsPath)
+		closers = append(closers, runner.metrics.Close)
+	}
+	if o.saveLibraryPath != "" {
+		closers = append(closers, func() error {
+			return saveGenomeLibrary(o.saveLibraryPath, world.LiveGenomes())
+		})
+	}
+	return runner, func() {
+		runner.Stop()
+		for i := range closers {
+			if err := closers[i](); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			exportCommand(os.Args[2:])
+			return
+		case "serve":
+			serveCommand(os.Args[2:])
+			return
+		case "tui":
+			tuiCommand(os.Args[2:])
+			return
+		case "sweep":
+			sweepCommand(os.Args[2:])
+			return
+		case "halloffame":
+			hallOfFameCommand(os.Args[2:])
+			return
+		}
+	}
+
+	options := simulationOptions{}
+	options.register(flag.CommandLine)
+	flag.Parse()
+
+	runner, stop := options.newRunner()
+	go runner.Run()
 	runUI := func() {
-		run(exporter)
+		run(runner)
 	}
 	pixelgl.Run(runUI)
+	stop()
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"hash/fnv"
+	"image"
+	"image/color"
+	"multicell/internal"
+	"strconv"
+
+	"github.com/faiface/pixel"
+)
+
+const (
+	VisionModeCellType = iota
+	VisionModeEnergy
+	VisionModeOrganism
+	VisionModeGenome
+	VisionModeWater
+	VisionModeSpecies
+	MaxVisionMode
+)
+
+var visionModeNames = []string{"cell type", "energy", "organism", "genome", "water", "species"}
+
+const spriteSize = 32
+
+// cellTypeColor is the dominant colour of the cell type sprite, for charts and text renderers
+func cellTypeColor(ct internal.CellType) color.RGBA {
+	switch ct {
+	case internal.CellTypeLeaf:
+		return color.RGBA{R: 70, G: 150, B: 60, A: 0xFF}
+	case internal.CellTypeTrunk:
+		return color.RGBA{R: 150, G: 100, B: 50, A: 0xFF}
+	case internal.CellTypeFlower:
+		return color.RGBA{R: 230, G: 110, B: 160, A: 0xFF}
+	case internal.CellTypeSeed:
+		return color.RGBA{R: 160, G: 160, B: 160, A: 0xFF}
+	case internal.CellTypeSprout:
+		return color.RGBA{R: 200, G: 210, B: 255, A: 0xFF}
+	case internal.CellTypeRoot:
+		return color.RGBA{R: 70, G: 80, B: 160, A: 0xFF}
+	case internal.CellTypeConnector:
+		return color.RGBA{R: 60, G: 180, B: 120, A: 0xFF}
+	}
+	panic(ct)
+}
+
+func (r *Resources) spriteFrame(ct internal.CellType, visionMode int) pixel.Rect {
+	rectNum := r.framesMap[ct]
+	if visionMode != VisionModeCellType {
+		rectNum += 1
+	}
+	return r.frames[rectNum]
+}
+
+func hashColor(source string) *pixel.RGBA {
+	hash := fnv.New32a()
+	hash.Write([]byte(source))
+	hashValue := hash.Sum32()
+	minColorValue := 0.3
+	rgbDivider := 255 * (1.0 - minColorValue)
+	return &pixel.RGBA{
+		R: minColorValue + float64(hashValue&0xFF)/rgbDivider,
+		G: minColorValue + float64((hashValue>>8)&0xFF)/rgbDivider,
+		B: minColorValue + float64((hashValue>>16)&0xFF)/rgbDivider,
+		A: 255,
+	}
+}
+
+// cellColorMask returns the mask applied to the cell sprite, nil means the sprite is drawn as is
+func cellColorMask(e *internal.WorldExport, pos internal.Position, visionMode int) *pixel.RGBA {
+	switch visionMode {
+	case VisionModeEnergy:
+		return &pixel.RGBA{R: 0.1 + 0.9*float64(e.Energy()[pos])/float64(internal.MaxEnergy)}
+	case VisionModeOrganism:
+		return hashColor(e.Organisms()[pos])
+	case VisionModeGenome:
+		return hashColor(e.Genomes()[pos])
+	case VisionModeWater:
+		return &pixel.RGBA{B: 0.1 + 0.9*float64(e.Water()[pos])/float64(internal.WaterMaxAmount)}
+	case VisionModeSpecies:
+		return hashColor(speciesKey(e.Species()[pos]))
+	}
+	return nil
+}
+
+// speciesKey is hashed into the species colour, the same in every viewer
+func speciesKey(species int) string {
+	return "species " + strconv.Itoa(species)
+}
+
+// cellFlatColor is a single colour for the cell, used where sprites can not be drawn
+func cellFlatColor(e *internal.WorldExport, pos internal.Position, visionMode int) color.RGBA {
+	mask := cellColorMask(e, pos, visionMode)
+	if mask == nil {
+		return cellTypeColor(e.CellTypes()[pos])
+	}
+	return color.RGBA{
+		R: uint8(min(1, mask.R) * 0xFF), G: uint8(min(1, mask.G) * 0xFF), B: uint8(min(1, mask.B) * 0xFF), A: 0xFF,
+	}
+}
+
+// FrameRenderer draws the same sprite based frames as the window without GL
+type FrameRenderer struct {
+	resources *Resources
+	tileSize  int
+}
+
+func NewFrameRenderer(resources *Resources, tileSize int) *FrameRenderer {
+	return &FrameRenderer{resources: resources, tileSize: max(1, tileSize)}
+}
+
+func (r *FrameRenderer) Render(e internal.WorldExport, visionMode int) *image.RGBA {
+	size := internal.WorldSize * r.tileSize
+	frame := image.NewRGBA(image.Rect(0, 0, size, size))
+	for i := 3; i < len(frame.Pix); i += 4 {
+		frame.Pix[i] = 0xFF
+	}
+	sheetHeight := r.resources.sheetImage.Bounds().Dy()
+	for pos, ct := range e.CellTypes() {
+		rect := r.resources.spriteFrame(ct, visionMode)
+		// pixel has the Y axis pointing up, images have it pointing down
+		spriteOrigin := image.Pt(int(rect.Min.X), sheetHeight-int(rect.Max.Y))
+		tileOrigin := image.Pt(int(pos.X)*r.tileSize, (internal.WorldSize-1-int(pos.Y))*r.tileSize)
+		mask := cellColorMask(&e, pos, visionMode)
+		for y := 0; y < r.tileSize; y++ {
+			for x := 0; x < r.tileSize; x++ {
+				src := r.resources.sheetImage.RGBAAt(
+					spriteOrigin.X+x*spriteSize/r.tileSize, spriteOrigin.Y+y*spriteSize/r.tileSize,
+				)
+				dst := image.Pt(tileOrigin.X+x, tileOrigin.Y+y)
+				frame.SetRGBA(dst.X, dst.Y, composeOver(frame.RGBAAt(dst.X, dst.Y), src, mask))
+			}
+		}
+	}
+	return frame
+}
+
+// composeOver blends a premultiplied sprite pixel tinted by the mask the way pixel does
+func composeOver(dst, src color.RGBA, mask *pixel.RGBA) color.RGBA {
+	s := pixel.RGBA{
+		R: float64(src.R) / 0xFF, G: float64(src.G) / 0xFF, B: float64(src.B) / 0xFF, A: float64(src.A) / 0xFF,
+	}
+	if mask != nil {
+		s = pixel.RGBA{
+			R: s.R * min(1, mask.R), G: s.G * min(1, mask.G), B: s.B * min(1, mask.B), A: s.A * min(1, mask.A),
+		}
+	}
+	blend := func(d uint8, value float64) uint8 {
+		return uint8(min(1, value+float64(d)/0xFF*(1-s.A)) * 0xFF)
+	}
+	return color.RGBA{R: blend(dst.R, s.R), G: blend(dst.G, s.G), B: blend(dst.B, s.B), A: blend(dst.A, s.A)}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"multicell/internal"
+	"sync"
+	"time"
+)
+
+const (
+	// RecentMetricsTurns is the amount of turns kept for the charts
+	RecentMetricsTurns = 500
+	// BaseTurnsPerSecond is the simulation rate at speed 1, one turn per rendered frame with VSync
+	BaseTurnsPerSecond = 60
+	// MaxSpeed runs the simulation as fast as possible
+	MaxSpeed = 0
+	// DefaultReseedTurns is the amount of turns without sprouts and flowers before the world is reseeded
+	DefaultReseedTurns = 200
+)
+
+type Runner struct {
+	world   *internal.World
+	history *internal.History
+	metrics internal.MetricsSink
+	reseed  internal.ReseedPolicy
+	// hallOfFame observes every turn when it is not nil
+	hallOfFame *internal.HallOfFame
+
+	mx             sync.Mutex
+	resume         *sync.Cond
+	paused         bool
+	stepsLeft      int
+	speed          int
+	done           bool
+	started        bool
+	stopped        bool
+	finished       chan struct{}
+	latest         internal.WorldExport
+	recentMetrics  []internal.TurnMetrics
+	turnsPerSecond float64
+	config         internal.Config
+	pendingConfig  *internal.Config
+}
+
+// NewRunner simulates the world, recording every turn into the history when it is not nil
+func NewRunner(world *internal.World, history *internal.History) *Runner {
+	r := &Runner{
+		world: world, history: history, paused: true, speed: 1, latest: internal.NewWorldExport(),
+		finished: make(chan struct{}),
+		reseed:   internal.NewExtinctionReseed(DefaultReseedTurns, internal.RandomGenomes{}),
+	}
+	r.resume = sync.NewCond(&r.mx)
+	if world != nil {
+		r.config = world.Config()
+	}
+	return r
+}
+
+// NewReplayRunner plays back the recorded history instead of simulating
+func NewReplayRunner(history *internal.History) *Runner {
+	return NewRunner(nil, history)
+}
+
+func (r *Runner) Run() {
+	defer close(r.finished)
+	r.mx.Lock()
+	r.started = true
+	r.mx.Unlock()
+	nextTurn := time.Now()
+	measureStart, measuredTurns := time.Now(), 0
+	for i := 0; i < SimulationSteps; i++ {
+		speed, stopped := r.waitForTurn()
+		if stopped {
+			break
+		}
+		if speed != MaxSpeed {
+			now := time.Now()
+			if nextTurn.After(now) {
+				time.Sleep(nextTurn.Sub(now))
+			} else {
+				nextTurn = now
+			}
+			nextTurn = nextTurn.Add(time.Second / time.Duration(speed*BaseTurnsPerSecond))
+		}
+
+		_, ok := r.Advance()
+		if !ok {
+			break
+		}
+
+		measuredTurns += 1
+		if elapsed := time.Since(measureStart); elapsed >= time.Second {
+			r.mx.Lock()
+			r.turnsPerSecond = float64(measuredTurns) / elapsed.Seconds()
+			r.mx.Unlock()
+			measureStart, measuredTurns = time.Now(), 0
+		}
+	}
+	r.mx.Lock()
+	r.done = true
+	r.turnsPerSecond = 0
+	r.mx.Unlock()
+}
+
+// Advance runs a single turn right away, the headless commands use it instead of Run
+func (r *Runner) Advance() (internal.WorldExport, bool) {
+	export, ok := r.nextTurn()
+	if ok {
+		r.mx.Lock()
+		r.latest = export
+		r.recentMetrics = append(r.recentMetrics, export.Metrics())
+		if len(r.recentMetrics) > RecentMetricsTurns {
+			r.recentMetrics = r.recentMetrics[1:]
+		}
+		r.mx.Unlock()
+	}
+	return export, ok
+}
+
+func (r *Runner) nextTurn() (internal.WorldExport, bool) {
+	if r.world == nil {
+		first, _ := r.history.Turns()
+		latest := r.Latest()
+		return r.history.At(max(first, latest.Turn()+1))
+	}
+
+	r.mx.Lock()
+	if r.pendingConfig != nil {
+		r.config = *r.pendingConfig
+		// the pending config was validated by SetConfig
+		_ = r.world.SetConfig(r.config)
+		r.pendingConfig = nil
+	}
+	r.mx.Unlock()
+
+	r.world.Step()
+	export := r.world.Export()
+	if r.history != nil {
+		if err := r.history.Record(export); err != nil {
+			exitWithError(err)
+		}
+	}
+	if r.metrics != nil {
+		if err := r.metrics.Write(export.Metrics()); err != nil {
+			exitWithError(err)
+		}
+	}
+
+	if r.hallOfFame != nil {
+		r.hallOfFame.Observe(r.world)
+	}
+	r.world.ForgetDeadOrganisms()
+	r.reseed.AfterTurn(r.world)
+	return export, true
+}
+
+// waitForTurn blocks while the runner is paused and no single steps are requested
+func (r *Runner) waitForTurn() (int, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	for r.paused && r.stepsLeft == 0 && !r.stopped {
+		r.turnsPerSecond = 0
+		r.resume.Wait()
+	}
+	if r.paused {
+		r.stepsLeft = max(0, r.stepsLeft-1)
+	}
+	return r.speed, r.stopped
+}
+
+// Stop ends Run after the current turn and waits for it, so the sinks can be closed safely
+func (r *Runner) Stop() {
+	r.mx.Lock()
+	r.stopped = true
+	started := r.started
+	r.mx.Unlock()
+	r.resume.Broadcast()
+	if started {
+		<-r.finished
+	}
+}
+
+func (r *Runner) Latest() internal.WorldExport {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.latest
+}
+
+// RecentMetrics returns the metrics of every one of the last RecentMetricsTurns turns
+func (r *Runner) RecentMetrics() []internal.TurnMetrics {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return append([]internal.TurnMetrics(nil), r.recentMetrics...)
+}
+
+func (r *Runner) Paused() bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.paused
+}
+
+// SetPaused drops the single steps which were requested before, they only apply to the pause they were made in
+func (r *Runner) SetPaused(paused bool) {
+	r.mx.Lock()
+	r.paused = paused
+	r.stepsLeft = 0
+	r.mx.Unlock()
+	r.resume.Broadcast()
+}
+
+// Step runs a single turn while paused, it does nothing while the runner is running
+func (r *Runner) Step() {
+	r.mx.Lock()
+	if !r.paused {
+		r.mx.Unlock()
+		return
+	}
+	r.stepsLeft += 1
+	r.mx.Unlock()
+	r.resume.Broadcast()
+}
+
+// Speed returns the amount of turns per rendered frame, or MaxSpeed
+func (r *Runner) Speed() int {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.speed
+}
+
+func (r *Runner) SetSpeed(speed int) {
+	r.mx.Lock()
+	r.speed = max(MaxSpeed, speed)
+	r.mx.Unlock()
+}
+
+func (r *Runner) TurnsPerSecond() float64 {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.turnsPerSecond
+}
+
+// Config returns the world parameters, including a change which is not applied yet
+func (r *Runner) Config() internal.Config {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.pendingConfig != nil {
+		return *r.pendingConfig
+	}
+	return r.config
+}
+
+// SetConfig applies the parameters to the world before the next turn, invalid parameters are rejected at once
+func (r *Runner) SetConfig(config internal.Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	r.mx.Lock()
+	r.pendingConfig = &config
+	r.mx.Unlock()
+	return nil
+}
+
+// Genome looks the genome up in the simulated world, it is nil for replays and unknown IDs
+func (r *Runner) Genome(id string) *internal.Genome {
+	if r.world == nil {
+		return nil
+	}
+	return r.world.GetGenome(id)
+}
+
+func (r *Runner) History() *internal.History {
+	return r.history
+}
+
+func (r *Runner) Done() bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.done
+}
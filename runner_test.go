@@ -0,0 +1,36 @@
+package main
+
+import (
+	"multicell/internal"
+	"testing"
+)
+
+func TestStepsAreOnlyCountedWhilePaused(t *testing.T) {
+	r := NewRunner(internal.NewSeededWorld(internal.WorldSize, 1), nil)
+	r.SetPaused(false)
+	r.Step()
+	r.Step()
+	r.SetPaused(true)
+	r.Step()
+	if _, stopped := r.waitForTurn(); stopped {
+		t.Fatal("the runner stopped")
+	}
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.stepsLeft != 0 {
+		t.Fatalf("%d steps are left after the single requested step", r.stepsLeft)
+	}
+}
+
+func TestPausingDropsTheRequestedSteps(t *testing.T) {
+	r := NewRunner(internal.NewSeededWorld(internal.WorldSize, 1), nil)
+	r.Step()
+	r.Step()
+	r.SetPaused(false)
+	r.SetPaused(true)
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.stepsLeft != 0 {
+		t.Fatalf("%d steps from an earlier pause are left", r.stepsLeft)
+	}
+}
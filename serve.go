@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"multicell/internal"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const streamInterval = 50 * time.Millisecond
+
+type server struct {
+	runner *Runner
+}
+
+type serverStatus struct {
+	Turn           int
+	Paused         bool
+	Speed          int
+	TurnsPerSecond float64
+	Done           bool
+	Metrics        internal.TurnMetrics
+}
+
+type serverParams struct {
+	Speed  int
+	Config internal.Config
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for i := range methods {
+		if r.Method == methods[i] {
+			return true
+		}
+	}
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func (s *server) currentStatus() serverStatus {
+	latest := s.runner.Latest()
+	return serverStatus{
+		Turn: latest.Turn(), Paused: s.runner.Paused(), Speed: s.runner.Speed(),
+		TurnsPerSecond: s.runner.TurnsPerSecond(), Done: s.runner.Done(), Metrics: latest.Metrics(),
+	}
+}
+
+func (s *server) status(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, s.currentStatus())
+}
+
+func (s *server) control(action func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethods(w, r, http.MethodPost) {
+			return
+		}
+		action()
+		writeJSON(w, s.currentStatus())
+	}
+}
+
+// params accepts partial updates, missing fields keep their current values
+func (s *server) params(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	params := serverParams{Speed: s.runner.Speed(), Config: s.runner.Config()}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.runner.SetConfig(params.Config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.runner.SetSpeed(params.Speed)
+	}
+	writeJSON(w, params)
+}
+
+func (s *server) snapshot(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	latest := s.runner.Latest()
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=turn_%d.json", latest.Turn()))
+	writeJSON(w, latest)
+}
+
+func (s *server) genome(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	genome := s.runner.Genome(r.URL.Query().Get("id"))
+	if genome == nil {
+		http.Error(w, "genome not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, struct {
+		ID, ParentID string
+		Genes        []int
+	}{ID: genome.GetID(), ParentID: genome.GetParentID(), Genes: genesToInts(genome.GetGenes())})
+}
+
+// genesToInts keeps the genes readable in JSON, byte slices would be base64 encoded
+func genesToInts(genes []uint8) []int {
+	result := make([]int, len(genes))
+	for i := range genes {
+		result[i] = int(genes[i])
+	}
+	return result
+}
+
+func (s *server) stream(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+	ticker := time.NewTicker(streamInterval)
+	defer ticker.Stop()
+	lastTurn := -1
+	for {
+		select {
+		case <-conn.Closed():
+			return
+		case <-ticker.C:
+			latest := s.runner.Latest()
+			if latest.Turn() == lastTurn {
+				continue
+			}
+			lastTurn = latest.Turn()
+			if err := conn.WriteMessage(wsOpBinary, encodeStreamFrame(&latest)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func stringHash(s string) uint32 {
+	hash := fnv.New32a()
+	hash.Write([]byte(s))
+	return hash.Sum32()
+}
+
+// encodeStreamFrame packs the export as little endian turn, world size and cells count followed by
+// 21 bytes per cell: x, y, cell type, energy, water, organism, genome and species hashes
+func encodeStreamFrame(e *internal.WorldExport) []byte {
+	result := make([]byte, 0, 10+21*len(e.CellTypes()))
+	result = binary.LittleEndian.AppendUint32(result, uint32(e.Turn()))
+	result = binary.LittleEndian.AppendUint16(result, uint16(internal.WorldSize))
+	result = binary.LittleEndian.AppendUint32(result, uint32(len(e.CellTypes())))
+	for pos, ct := range e.CellTypes() {
+		result = binary.LittleEndian.AppendUint16(result, uint16(pos.X))
+		result = binary.LittleEndian.AppendUint16(result, uint16(pos.Y))
+		result = append(result, byte(ct))
+		result = binary.LittleEndian.AppendUint16(result, uint16(e.Energy()[pos]))
+		result = binary.LittleEndian.AppendUint16(result, uint16(e.Water()[pos]))
+		result = binary.LittleEndian.AppendUint32(result, stringHash(e.Organisms()[pos]))
+		result = binary.LittleEndian.AppendUint32(result, stringHash(e.Genomes()[pos]))
+		result = binary.LittleEndian.AppendUint32(result, stringHash(speciesKey(e.Species()[pos])))
+	}
+	return result
+}
+
+func serveCommand(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "127.0.0.1:8080", "address to listen on, only local clients can connect by default")
+	paused := flags.Bool("paused", false, "start with the simulation paused")
+	speed := flags.Int("speed", MaxSpeed, "turns per 1/60 of a second, 0 runs as fast as possible")
+	options := simulationOptions{}
+	options.register(flags)
+	_ = flags.Parse(args)
+
+	runner, stop := options.newRunner()
+	runner.SetPaused(*paused)
+	runner.SetSpeed(*speed)
+	go runner.Run()
+
+	web, err := fs.Sub(resources, "resources/web")
+	if err != nil {
+		exitWithError(err)
+	}
+	s := &server{runner: runner}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(web)))
+	mux.HandleFunc("/api/status", s.status)
+	mux.HandleFunc("/api/pause", s.control(func() { runner.SetPaused(true) }))
+	mux.HandleFunc("/api/resume", s.control(func() { runner.SetPaused(false) }))
+	mux.HandleFunc("/api/step", s.control(runner.Step))
+	mux.HandleFunc("/api/params", s.params)
+	mux.HandleFunc("/api/snapshot", s.snapshot)
+	mux.HandleFunc("/api/genome", s.genome)
+	mux.HandleFunc("/ws", s.stream)
+
+	// the header timeout keeps slow clients from holding connections open forever
+	httpServer := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		<-interrupt
+		_ = httpServer.Close()
+	}()
+	fmt.Printf("serving on %s\n", *addr)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		stop()
+		exitWithError(err)
+	}
+	stop()
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"multicell/internal"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// sweepParam is either a list of values or a from:to range, split into steps for grids
+type sweepParam struct {
+	name     string
+	values   []float64
+	from, to float64
+	steps    int
+	isRange  bool
+}
+
+func (p *sweepParam) gridValues() []float64 {
+	if !p.isRange {
+		return p.values
+	}
+	if p.steps < 2 {
+		return []float64{p.from}
+	}
+	result := make([]float64, p.steps)
+	for i := range result {
+		result[i] = p.from + (p.to-p.from)*float64(i)/float64(p.steps-1)
+	}
+	return result
+}
+
+func (p *sweepParam) sample(random *internal.Random) float64 {
+	if !p.isRange {
+		return p.values[random.Uint32()%uint32(len(p.values))]
+	}
+	return p.from + (p.to-p.from)*float64(random.Float32())
+}
+
+type sweepParams []sweepParam
+
+func (p *sweepParams) String() string {
+	return ""
+}
+
+// Set parses Name=v1,v2,v3 lists and Name=from:to[:steps] ranges
+func (p *sweepParams) Set(value string) error {
+	name, values, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("parameter %q should look like Name=v1,v2 or Name=from:to[:steps]", value)
+	}
+	if err := (&internal.Config{}).SetField(name, 0); err != nil {
+		return fmt.Errorf("%w, known parameters: %s", err, strings.Join(internal.ConfigFieldNames(), ", "))
+	}
+	param := sweepParam{name: name, steps: 5}
+	if strings.Contains(values, ":") {
+		parts := strings.Split(values, ":")
+		if len(parts) > 3 {
+			return fmt.Errorf("range %q should look like from:to[:steps]", values)
+		}
+		param.isRange = true
+		var err error
+		if param.from, err = strconv.ParseFloat(parts[0], 64); err != nil {
+			return err
+		}
+		if param.to, err = strconv.ParseFloat(parts[1], 64); err != nil {
+			return err
+		}
+		if len(parts) == 3 {
+			if param.steps, err = strconv.Atoi(parts[2]); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, part := range strings.Split(values, ",") {
+			number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return err
+			}
+			param.values = append(param.values, number)
+		}
+	}
+	checked := param.values
+	if param.isRange {
+		// the ends of a range are enough, the values between them are valid as well
+		checked = []float64{param.from, param.to}
+	}
+	for _, value := range checked {
+		config := internal.DefaultConfig()
+		if err := config.SetField(name, value); err != nil {
+			return err
+		}
+	}
+	*p = append(*p, param)
+	return nil
+}
+
+// points returns every combination of the parameters in grid mode or random samples otherwise
+func (p sweepParams) points(grid bool, samples int, random *internal.Random) [][]float64 {
+	if !grid {
+		result := make([][]float64, samples)
+		for i := range result {
+			result[i] = make([]float64, len(p))
+			for j := range p {
+				result[i][j] = p[j].sample(random)
+			}
+		}
+		return result
+	}
+	result := [][]float64{{}}
+	for i := range p {
+		var next [][]float64
+		for _, point := range result {
+			for _, value := range p[i].gridValues() {
+				next = append(next, append(append([]float64(nil), point...), value))
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+type sweepRun struct {
+	index  int
+	seed   int64
+	values []float64
+	config internal.Config
+}
+
+type sweepResult struct {
+	run            sweepRun
+	turns          int
+	extinct        bool
+	peakPopulation int
+	peakOrganisms  int
+	peakGenomes    int
+	finalGenomes   int
+	mutations      int
+}
+
+// runSweepWorld simulates a single world without reseeding until extinction or the turn limit
+func runSweepWorld(run sweepRun, maxTurns int) sweepResult {
+	world := internal.NewSeededWorld(internal.WorldSize, run.seed)
+	if err := world.SetConfig(run.config); err != nil {
+		exitWithError(err)
+	}
+	result := sweepResult{run: run}
+	world.Events().Subscribe(func(e internal.Event) {
+		if e.Header().Type == internal.EventMutationOccurred {
+			result.mutations += 1
+		}
+	})
+	world.SeedWorld(internal.DefaultSeedChance, internal.RandomGenomes{})
+	for result.turns < maxTurns {
+		world.Step()
+		result.turns += 1
+		metrics := world.Metrics()
+		result.peakPopulation = max(result.peakPopulation, metrics.TotalCells())
+		result.peakOrganisms = max(result.peakOrganisms, metrics.Organisms)
+		result.peakGenomes = max(result.peakGenomes, metrics.Genomes)
+		result.finalGenomes = metrics.Genomes
+		world.ForgetDeadOrganisms()
+		if metrics.TotalCells() == 0 {
+			result.extinct = true
+			break
+		}
+	}
+	return result
+}
+
+func sweepHeader(params sweepParams) []string {
+	result := []string{"run", "seed"}
+	for i := range params {
+		result = append(result, params[i].name)
+	}
+	return append(
+		result, "turns", "extinct", "peak_population", "peak_organisms", "peak_genomes", "final_genomes", "mutations",
+	)
+}
+
+func (r *sweepResult) row() []string {
+	result := []string{strconv.Itoa(r.run.index), strconv.FormatInt(r.run.seed, 10)}
+	for i := range r.run.values {
+		result = append(result, strconv.FormatFloat(r.run.values[i], 'g', -1, 64))
+	}
+	return append(
+		result, strconv.Itoa(r.turns), strconv.FormatBool(r.extinct), strconv.Itoa(r.peakPopulation),
+		strconv.Itoa(r.peakOrganisms), strconv.Itoa(r.peakGenomes), strconv.Itoa(r.finalGenomes),
+		strconv.Itoa(r.mutations),
+	)
+}
+
+func writeSweepResults(path string, params sweepParams, results []sweepResult) error {
+	if path == "" {
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		writeRow := func(row []string) {
+			_, _ = io.WriteString(table, strings.Join(row, "\t")+"\n")
+		}
+		writeRow(sweepHeader(params))
+		for i := range results {
+			writeRow(results[i].row())
+		}
+		return table.Flush()
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err := writer.Write(sweepHeader(params)); err != nil {
+		return err
+	}
+	for i := range results {
+		if err := writer.Write(results[i].row()); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func sweepCommand(args []string) {
+	flags := flag.NewFlagSet("sweep", flag.ExitOnError)
+	var params sweepParams
+	flags.Var(
+		&params, "param",
+		"config parameter to sweep as Name=v1,v2,v3 or Name=from:to[:steps], may be repeated",
+	)
+	mode := flags.String("mode", "grid", "grid runs every combination, random samples the parameters")
+	samples := flags.Int("samples", 10, "amount of random parameter samples")
+	runs := flags.Int("runs", 3, "worlds with different seeds for every parameter combination")
+	seed := flags.Int64("seed", 1, "seed of the first run of every combination, the following runs increment it")
+	turns := flags.Int("turns", 5000, "turn limit of a single world")
+	workers := flags.Int("workers", runtime.NumCPU(), "amount of worlds simulated in parallel")
+	out := flags.String("out", "", "CSV file to write the results to, an aligned table is printed when empty")
+	_ = flags.Parse(args)
+
+	if *mode != "grid" && *mode != "random" {
+		exitWithError(fmt.Errorf("unknown sweep mode %q", *mode))
+	}
+	var sweepRuns []sweepRun
+	for _, point := range params.points(*mode == "grid", *samples, internal.NewRandom(*seed)) {
+		config := internal.DefaultConfig()
+		// the values are reported as applied, integer parameters are rounded down
+		applied := make([]float64, len(params))
+		for i := range params {
+			if err := config.SetField(params[i].name, point[i]); err != nil {
+				exitWithError(err)
+			}
+			applied[i], _ = config.Field(params[i].name)
+		}
+		// every combination runs on the same seeds, so the seeds do not blur the effect of the parameters
+		for i := 0; i < *runs; i++ {
+			sweepRuns = append(sweepRuns, sweepRun{
+				index: len(sweepRuns), seed: *seed + int64(i), values: applied, config: config,
+			})
+		}
+	}
+
+	results := make([]sweepResult, len(sweepRuns))
+	queue := make(chan sweepRun)
+	var wg sync.WaitGroup
+	var progressMx sync.Mutex
+	finished := 0
+	wg.Add(max(1, *workers))
+	for i := 0; i < max(1, *workers); i++ {
+		go func() {
+			defer wg.Done()
+			for run := range queue {
+				results[run.index] = runSweepWorld(run, *turns)
+				progressMx.Lock()
+				finished += 1
+				fmt.Fprintf(
+					os.Stderr, "run %d/%d finished after %d turns\n", finished, len(sweepRuns),
+					results[run.index].turns,
+				)
+				progressMx.Unlock()
+			}
+		}()
+	}
+	for i := range sweepRuns {
+		queue <- sweepRuns[i]
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := writeSweepResults(*out, params, results); err != nil {
+		exitWithError(err)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"multicell/internal"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	tuiFrameInterval = 100 * time.Millisecond
+	// tuiStatusLines are reserved below the world view
+	tuiStatusLines = 3
+)
+
+type tuiKey int
+
+const (
+	tuiKeyRune tuiKey = iota
+	tuiKeyUp
+	tuiKeyDown
+	tuiKeyLeft
+	tuiKeyRight
+)
+
+type tuiInput struct {
+	key  tuiKey
+	char byte
+}
+
+// terminalSize is asked for at the start and after every resize signal
+func terminalSize() (rows, cols int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 24, 80
+	}
+	return height, width
+}
+
+func readTerminalInput(inputs chan<- tuiInput) {
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(inputs)
+			return
+		}
+		for i := 0; i < n; i++ {
+			if buf[i] == 0x1b && i+2 < n && buf[i+1] == '[' {
+				switch buf[i+2] {
+				case 'A':
+					inputs <- tuiInput{key: tuiKeyUp}
+				case 'B':
+					inputs <- tuiInput{key: tuiKeyDown}
+				case 'C':
+					inputs <- tuiInput{key: tuiKeyRight}
+				case 'D':
+					inputs <- tuiInput{key: tuiKeyLeft}
+				}
+				i += 2
+				continue
+			}
+			inputs <- tuiInput{key: tuiKeyRune, char: buf[i]}
+		}
+	}
+}
+
+// terminalCanvas accumulates a frame with the minimal amount of colour escape sequences
+type terminalCanvas struct {
+	builder   strings.Builder
+	trueColor bool
+	fg, bg    *color.RGBA
+}
+
+func (c *terminalCanvas) colorCode(background bool, value color.RGBA) string {
+	layer := 38
+	if background {
+		layer = 48
+	}
+	if c.trueColor {
+		return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", layer, value.R, value.G, value.B)
+	}
+	// closest colour of the 6x6x6 cube of the 256 colour palette
+	cube := func(v uint8) int { return (int(v)*5 + 127) / 255 }
+	return fmt.Sprintf("\x1b[%d;5;%dm", layer, 16+36*cube(value.R)+6*cube(value.G)+cube(value.B))
+}
+
+// halfBlock draws two vertically stacked tiles in one character, nil tiles are left empty
+func (c *terminalCanvas) halfBlock(upper, lower *color.RGBA) {
+	if upper == nil && lower == nil {
+		if c.fg != nil || c.bg != nil {
+			c.builder.WriteString("\x1b[0m")
+			c.fg, c.bg = nil, nil
+		}
+		c.builder.WriteByte(' ')
+		return
+	}
+	glyph := "▀"
+	if upper == nil {
+		upper, lower = lower, nil
+		glyph = "▄"
+	}
+	if c.fg == nil || *c.fg != *upper {
+		c.builder.WriteString(c.colorCode(false, *upper))
+		c.fg = upper
+	}
+	if lower == nil {
+		if c.bg != nil {
+			c.builder.WriteString("\x1b[49m")
+			c.bg = nil
+		}
+	} else if c.bg == nil || *c.bg != *lower {
+		c.builder.WriteString(c.colorCode(true, *lower))
+		c.bg = lower
+	}
+	c.builder.WriteString(glyph)
+}
+
+func (c *terminalCanvas) endLine() {
+	c.builder.WriteString("\x1b[0m\x1b[K\r\n")
+	c.fg, c.bg = nil, nil
+}
+
+func drawTerminalFrame(
+	e *internal.WorldExport, runner *Runner, visionMode int, trueColor bool, cameraX, cameraY int64, rows, cols int,
+) string {
+	// every row shows two tiles, the status lines are taken off before the view is clamped to the world
+	viewRows := min(max(1, rows-tuiStatusLines), (internal.WorldSize+1)/2)
+	viewCols := min(cols, internal.WorldSize)
+	canvas := terminalCanvas{trueColor: trueColor}
+	canvas.builder.WriteString("\x1b[H")
+	tileColor := func(x, y int64) *color.RGBA {
+		pos := internal.NewPosition(x, y)
+		if _, found := e.CellTypes()[pos]; !found {
+			return nil
+		}
+		result := cellFlatColor(e, pos, visionMode)
+		return &result
+	}
+	for row := 0; row < viewRows; row++ {
+		// the world has the Y axis pointing up, so the top row shows the highest tiles
+		upperY := cameraY + int64(2*(viewRows-1-row)) + 1
+		for col := 0; col < viewCols; col++ {
+			x := cameraX + int64(col)
+			canvas.halfBlock(tileColor(x, upperY), tileColor(x, upperY-1))
+		}
+		canvas.endLine()
+	}
+
+	metrics := e.Metrics()
+	state := "running"
+	if runner.Paused() {
+		state = "PAUSED"
+	}
+	fmt.Fprintf(
+		&canvas.builder, "turn %d %s, speed %s, %.0f turns/s, vision %s, view %d:%d\x1b[K\r\n",
+		e.Turn(), state, speedLabel(runner.Speed()), runner.TurnsPerSecond(), visionModeNames[visionMode],
+		cameraX, cameraY,
+	)
+	fmt.Fprintf(
+		&canvas.builder, "organisms %d, genomes %d, species %d", metrics.Organisms, metrics.Genomes, metrics.Species,
+	)
+	for ct := internal.CellType(0); ct < internal.MaxCellType; ct++ {
+		fmt.Fprintf(&canvas.builder, ", %s %d", ct, metrics.Cells[ct])
+	}
+	canvas.builder.WriteString("\x1b[K\r\n")
+	canvas.builder.WriteString(
+		"space pause, s step, 1/2/3 +/- speed, q e w r t y vision, arrows or hjkl pan, x quit\x1b[K\x1b[J",
+	)
+	return canvas.builder.String()
+}
+
+func tuiCommand(args []string) {
+	flags := flag.NewFlagSet("tui", flag.ExitOnError)
+	colors := flags.String("colors", "auto", "colour mode: truecolor, 256 or auto from $COLORTERM")
+	options := simulationOptions{}
+	options.register(flags)
+	_ = flags.Parse(args)
+	trueColor := *colors == "truecolor" ||
+		*colors == "auto" && (os.Getenv("COLORTERM") == "truecolor" || os.Getenv("COLORTERM") == "24bit")
+
+	// the runner is created before the raw mode, its setup errors exit with the terminal untouched
+	runner, stop := options.newRunner()
+	savedState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		stop()
+		exitWithError(fmt.Errorf("terminal is required: %w", err))
+	}
+	defer func() {
+		_ = term.Restore(int(os.Stdin.Fd()), savedState)
+		fmt.Print("\x1b[0m\x1b[?25h\x1b[2J\x1b[H")
+		// stop exits on errors closing the outputs, so it runs once the terminal is restored
+		stop()
+	}()
+	fmt.Print("\x1b[?25l\x1b[2J")
+	go runner.Run()
+
+	inputs := make(chan tuiInput, 16)
+	go readTerminalInput(inputs)
+	ticker := time.NewTicker(tuiFrameInterval)
+	defer ticker.Stop()
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+	rows, cols := terminalSize()
+	visionMode := VisionModeCellType
+	cameraX, cameraY := int64(0), int64(0)
+	visionKeys := map[byte]int{
+		'q': VisionModeCellType, 'e': VisionModeEnergy, 'w': VisionModeOrganism, 'r': VisionModeGenome,
+		't': VisionModeWater, 'y': VisionModeSpecies,
+	}
+	for {
+		select {
+		case input, ok := <-inputs:
+			if !ok {
+				return
+			}
+			switch input.key {
+			case tuiKeyUp:
+				cameraY += 2
+			case tuiKeyDown:
+				cameraY -= 2
+			case tuiKeyLeft:
+				cameraX -= 1
+			case tuiKeyRight:
+				cameraX += 1
+			case tuiKeyRune:
+				if mode, found := visionKeys[input.char]; found {
+					visionMode = mode
+				}
+				switch input.char {
+				case 'x', 3:
+					return
+				case ' ':
+					runner.SetPaused(!runner.Paused())
+				case 's':
+					runner.Step()
+				case '1':
+					runner.SetSpeed(1)
+				case '2':
+					runner.SetSpeed(10)
+				case '3':
+					runner.SetSpeed(MaxSpeed)
+				case '+', '=':
+					if runner.Speed() != MaxSpeed {
+						runner.SetSpeed(runner.Speed() + 1)
+					}
+				case '-':
+					if runner.Speed() > 1 {
+						runner.SetSpeed(runner.Speed() - 1)
+					}
+				case 'k':
+					cameraY += 2
+				case 'j':
+					cameraY -= 2
+				case 'h':
+					cameraX -= 1
+				case 'l':
+					cameraX += 1
+				}
+			}
+			cameraX = (cameraX + internal.WorldSize) % internal.WorldSize
+			cameraY = (cameraY + internal.WorldSize) % internal.WorldSize
+		case <-resized:
+			rows, cols = terminalSize()
+		case <-ticker.C:
+			latest := runner.Latest()
+			fmt.Print(drawTerminalFrame(&latest, runner, visionMode, trueColor, cameraX, cameraY, rows, cols))
+		}
+	}
+}
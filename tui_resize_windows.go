@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// notifyResize does nothing, windows consoles do not signal a resize and keep the size of the start
+func notifyResize(chan<- os.Signal) {}